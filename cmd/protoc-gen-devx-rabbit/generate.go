@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+const (
+	contextPackage = protogen.GoImportPath("context")
+	rabbitPackage  = protogen.GoImportPath("github.com/xBlaz3kx/DevX/rabbit")
+)
+
+// generateFile generates a _rabbit.pb.go file with the clients and servers of the services in the file
+func generateFile(gen *protogen.Plugin, file *protogen.File) *protogen.GeneratedFile {
+	if len(file.Services) == 0 {
+		return nil
+	}
+
+	filename := file.GeneratedFilenamePrefix + "_rabbit.pb.go"
+	g := gen.NewGeneratedFile(filename, file.GoImportPath)
+
+	g.P("// Code generated by protoc-gen-devx-rabbit. DO NOT EDIT.")
+	g.P("// versions:")
+	g.P("// - protoc-gen-devx-rabbit v", version)
+	g.P("// source: ", file.Desc.Path())
+	g.P()
+	g.P("package ", file.GoPackageName)
+	g.P()
+
+	for _, service := range file.Services {
+		generateService(g, service)
+	}
+
+	return g
+}
+
+func generateService(g *protogen.GeneratedFile, service *protogen.Service) {
+	methods := unaryMethods(service)
+
+	generateTopics(g, service, methods)
+	generateClient(g, service, methods)
+	generateServer(g, service, methods)
+}
+
+// unaryMethods returns the methods that can be called over RabbitMQ
+func unaryMethods(service *protogen.Service) []*protogen.Method {
+	methods := []*protogen.Method{}
+	for _, method := range service.Methods {
+		if method.Desc.IsStreamingClient() || method.Desc.IsStreamingServer() {
+			continue
+		}
+
+		methods = append(methods, method)
+	}
+
+	return methods
+}
+
+func topicName(service *protogen.Service, method *protogen.Method) string {
+	return fmt.Sprintf("%s_%s_Topic", service.GoName, method.GoName)
+}
+
+func generateTopics(g *protogen.GeneratedFile, service *protogen.Service, methods []*protogen.Method) {
+	g.P("// Topics of the ", service.GoName, " methods. The topics start with the full service name, followed by the method name.")
+	g.P("const (")
+	for _, method := range methods {
+		topic := fmt.Sprintf("%s.%s", service.Desc.FullName(), method.Desc.Name())
+		g.P(topicName(service, method), " ", g.QualifiedGoIdent(rabbitPackage.Ident("Topic")), " = ", fmt.Sprintf("%q", topic))
+	}
+	g.P(")")
+	g.P()
+}
+
+func generateClient(g *protogen.GeneratedFile, service *protogen.Service, methods []*protogen.Method) {
+	clientName := service.GoName + "RabbitClient"
+	clientStruct := unexport(clientName)
+
+	g.P("// ", clientName, " is the client API for ", service.GoName, " over RabbitMQ.")
+	g.P("// Errors replied by the server are returned as *rabbit.RPCError.")
+	g.P("type ", clientName, " interface {")
+	for _, method := range methods {
+		g.P(method.Comments.Leading, clientSignature(g, method))
+	}
+	g.P("}")
+	g.P()
+
+	g.P("type ", clientStruct, " struct {")
+	g.P("publisher *", g.QualifiedGoIdent(rabbitPackage.Ident("PublisherPool")))
+	g.P("}")
+	g.P()
+
+	g.P("// New", clientName, " creates a client and declares the publications of the ", service.GoName, " requests.")
+	g.P("func New", clientName, "(publisher *", g.QualifiedGoIdent(rabbitPackage.Ident("PublisherPool")), ") ", clientName, " {")
+	for _, method := range methods {
+		g.P("publisher.DeclarePublication(", topicName(service, method), ", new(", g.QualifiedGoIdent(method.Input.GoIdent), "))")
+	}
+	g.P("return &", clientStruct, "{publisher: publisher}")
+	g.P("}")
+	g.P()
+
+	for _, method := range methods {
+		g.P("func (c *", clientStruct, ") ", clientSignature(g, method), " {")
+		g.P("out := new(", g.QualifiedGoIdent(method.Output.GoIdent), ")")
+		g.P("err := c.publisher.InvokeRPC(ctx, ", topicName(service, method), ", in, out, opts...)")
+		g.P("if err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+		g.P("return out, nil")
+		g.P("}")
+		g.P()
+	}
+}
+
+func clientSignature(g *protogen.GeneratedFile, method *protogen.Method) string {
+	return fmt.Sprintf("%s(ctx %s, in *%s, opts ...%s) (*%s, error)",
+		method.GoName,
+		g.QualifiedGoIdent(contextPackage.Ident("Context")),
+		g.QualifiedGoIdent(method.Input.GoIdent),
+		g.QualifiedGoIdent(rabbitPackage.Ident("PublishOpt")),
+		g.QualifiedGoIdent(method.Output.GoIdent),
+	)
+}
+
+func generateServer(g *protogen.GeneratedFile, service *protogen.Service, methods []*protogen.Method) {
+	serverName := service.GoName + "RabbitServer"
+
+	g.P("// ", serverName, " is the server API for ", service.GoName, " over RabbitMQ.")
+	g.P("// Returning a *rabbit.RPCError replies with its error code, other errors are replied with the ApplicationError code.")
+	g.P("type ", serverName, " interface {")
+	for _, method := range methods {
+		g.P(method.Comments.Leading, serverSignature(g, method))
+	}
+	g.P("}")
+	g.P()
+
+	g.P("// Register", serverName, " creates a durable consumer for each method of ", service.GoName, ".")
+	g.P("// The queues are named after the method topics, so the instances of the server share the requests.")
+	g.P("func Register", serverName, "(consumers *", g.QualifiedGoIdent(rabbitPackage.Ident("ConsumerFactory")),
+		", publisher *", g.QualifiedGoIdent(rabbitPackage.Ident("PublisherPool")),
+		", srv ", serverName,
+		", opts ...", g.QualifiedGoIdent(rabbitPackage.Ident("ConsumerOpt")), ") error {")
+	for _, method := range methods {
+		topic := topicName(service, method)
+		g.P("if _, err := consumers.NewConsumer(")
+		g.P(g.QualifiedGoIdent(rabbitPackage.Ident("CentralExchange")), ",")
+		g.P(topic, ",")
+		g.P(topic, ".String(),")
+		g.P(g.QualifiedGoIdent(rabbitPackage.Ident("NewRPCHandler")), "(publisher, func() *", g.QualifiedGoIdent(method.Input.GoIdent), " { return new(", g.QualifiedGoIdent(method.Input.GoIdent), ") }, srv.", method.GoName, "),")
+		g.P("true,")
+		g.P("append([]", g.QualifiedGoIdent(rabbitPackage.Ident("ConsumerOpt")), "{", g.QualifiedGoIdent(rabbitPackage.Ident("WithMessageType")), "(new(", g.QualifiedGoIdent(method.Input.GoIdent), "))}, opts...)...,")
+		g.P("); err != nil {")
+		g.P("return err")
+		g.P("}")
+		g.P()
+	}
+	g.P("return nil")
+	g.P("}")
+	g.P()
+}
+
+func serverSignature(g *protogen.GeneratedFile, method *protogen.Method) string {
+	return fmt.Sprintf("%s(ctx %s, in *%s) (*%s, error)",
+		method.GoName,
+		g.QualifiedGoIdent(contextPackage.Ident("Context")),
+		g.QualifiedGoIdent(method.Input.GoIdent),
+		g.QualifiedGoIdent(method.Output.GoIdent),
+	)
+}
+
+func unexport(name string) string {
+	return strings.ToLower(name[:1]) + name[1:]
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func newTestRequest() *pluginpb.CodeGeneratorRequest {
+	message := func(name string) *descriptorpb.DescriptorProto {
+		return &descriptorpb.DescriptorProto{
+			Name: proto.String(name),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:     proto.String("id"),
+				Number:   proto.Int32(1),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				JsonName: proto.String("id"),
+			}},
+		}
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("charge_point.proto"),
+		Package: proto.String("chargepoint"),
+		Syntax:  proto.String("proto3"),
+		Options: &descriptorpb.FileOptions{
+			GoPackage: proto.String("example.com/chargepoint;chargepoint"),
+		},
+		MessageType: []*descriptorpb.DescriptorProto{
+			message("GetStatusRequest"),
+			message("GetStatusResponse"),
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("ChargePointService"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{
+					Name:       proto.String("GetStatus"),
+					InputType:  proto.String(".chargepoint.GetStatusRequest"),
+					OutputType: proto.String(".chargepoint.GetStatusResponse"),
+				},
+				{
+					Name:            proto.String("StreamStatus"),
+					InputType:       proto.String(".chargepoint.GetStatusRequest"),
+					OutputType:      proto.String(".chargepoint.GetStatusResponse"),
+					ServerStreaming: proto.Bool(true),
+				},
+			},
+		}},
+	}
+
+	return &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"charge_point.proto"},
+		ProtoFile:      []*descriptorpb.FileDescriptorProto{file},
+	}
+}
+
+func TestGenerateFile(t *testing.T) {
+	gen, err := protogen.Options{}.New(newTestRequest())
+	require.NoError(t, err)
+
+	for _, file := range gen.Files {
+		if file.Generate {
+			generateFile(gen, file)
+		}
+	}
+
+	response := gen.Response()
+	require.Nil(t, response.Error)
+	require.Len(t, response.File, 1)
+
+	generated := response.File[0]
+	assert.Equal(t, "example.com/chargepoint/charge_point_rabbit.pb.go", generated.GetName())
+
+	content := generated.GetContent()
+	_, err = parser.ParseFile(token.NewFileSet(), generated.GetName(), content, parser.AllErrors)
+	require.NoError(t, err)
+
+	assert.Contains(t, content, `ChargePointService_GetStatus_Topic rabbit.Topic = "chargepoint.ChargePointService.GetStatus"`)
+	assert.NotContains(t, content, "rabbit.Exchange")
+	assert.Contains(t, content, "type ChargePointServiceRabbitClient interface")
+	assert.Contains(t, content, "c.publisher.InvokeRPC(ctx, ChargePointService_GetStatus_Topic, in, out, opts...)")
+	assert.Contains(t, content, "func NewChargePointServiceRabbitClient(publisher *rabbit.PublisherPool) ChargePointServiceRabbitClient")
+	assert.Contains(t, content, "GetStatus(ctx context.Context, in *GetStatusRequest, opts ...rabbit.PublishOpt) (*GetStatusResponse, error)")
+	assert.Contains(t, content, "type ChargePointServiceRabbitServer interface")
+	assert.Contains(t, content, "func RegisterChargePointServiceRabbitServer(consumers *rabbit.ConsumerFactory, publisher *rabbit.PublisherPool, srv ChargePointServiceRabbitServer, opts ...rabbit.ConsumerOpt) error")
+
+	assert.Contains(t, content, "publisher.DeclarePublication(ChargePointService_GetStatus_Topic, new(GetStatusRequest))")
+	assert.Contains(t, content, "append([]rabbit.ConsumerOpt{rabbit.WithMessageType(new(GetStatusRequest))}, opts...)...")
+	assert.Contains(t, content, "rabbit.NewRPCHandler(publisher, func() *GetStatusRequest { return new(GetStatusRequest) }, srv.GetStatus)")
+
+	// Streaming methods are skipped
+	assert.NotContains(t, content, "StreamStatus")
+}
+
+func TestGenerateFile_NoServices(t *testing.T) {
+	request := newTestRequest()
+	request.ProtoFile[0].Service = nil
+
+	gen, err := protogen.Options{}.New(request)
+	require.NoError(t, err)
+
+	for _, file := range gen.Files {
+		assert.Nil(t, generateFile(gen, file))
+	}
+	assert.Empty(t, gen.Response().File)
+}
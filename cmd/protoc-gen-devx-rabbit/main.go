@@ -0,0 +1,43 @@
+// Command protoc-gen-devx-rabbit is a protoc plugin, which generates typed RabbitMQ clients and servers from the
+// protobuf service definitions. Clients publish the requests with PublisherPool.PublishRPC and servers are registered
+// as consumers on the ConsumerFactory.
+//
+// The topic of each method is derived from the full service and the method name, e.g.
+// "chargepoint.ChargePointService.GetStatus".
+// Streaming methods are not supported and are skipped.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+const version = "0.1.0"
+
+func main() {
+	showVersion := flag.Bool("version", false, "print the version and exit")
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Printf("protoc-gen-devx-rabbit %s\n", version)
+		os.Exit(0)
+	}
+
+	protogen.Options{ParamFunc: flag.CommandLine.Set}.Run(func(gen *protogen.Plugin) error {
+		gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+
+		for _, file := range gen.Files {
+			if !file.Generate {
+				continue
+			}
+
+			generateFile(gen, file)
+		}
+
+		return nil
+	})
+}
@@ -68,6 +68,51 @@ func (_c *MockClient_Connect_Call) RunAndReturn(run func(context.Context) error)
 	return _c
 }
 
+// ConnectionState provides a mock function with no fields
+func (_m *MockClient) ConnectionState() mqtt.ConnectionState {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConnectionState")
+	}
+
+	var r0 mqtt.ConnectionState
+	if rf, ok := ret.Get(0).(func() mqtt.ConnectionState); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(mqtt.ConnectionState)
+	}
+
+	return r0
+}
+
+// MockClient_ConnectionState_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ConnectionState'
+type MockClient_ConnectionState_Call struct {
+	*mock.Call
+}
+
+// ConnectionState is a helper method to define mock.On call
+func (_e *MockClient_Expecter) ConnectionState() *MockClient_ConnectionState_Call {
+	return &MockClient_ConnectionState_Call{Call: _e.mock.On("ConnectionState")}
+}
+
+func (_c *MockClient_ConnectionState_Call) Run(run func()) *MockClient_ConnectionState_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockClient_ConnectionState_Call) Return(_a0 mqtt.ConnectionState) *MockClient_ConnectionState_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockClient_ConnectionState_Call) RunAndReturn(run func() mqtt.ConnectionState) *MockClient_ConnectionState_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Disconnect provides a mock function with given fields: ctx
 func (_m *MockClient) Disconnect(ctx context.Context) error {
 	ret := _m.Called(ctx)
@@ -204,6 +249,39 @@ func (_c *MockClient_Name_Call) RunAndReturn(run func() string) *MockClient_Name
 	return _c
 }
 
+// OnConnectionStateChange provides a mock function with given fields: handler
+func (_m *MockClient) OnConnectionStateChange(handler mqtt.ConnectionStateHandler) {
+	_m.Called(handler)
+}
+
+// MockClient_OnConnectionStateChange_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'OnConnectionStateChange'
+type MockClient_OnConnectionStateChange_Call struct {
+	*mock.Call
+}
+
+// OnConnectionStateChange is a helper method to define mock.On call
+//   - handler mqtt.ConnectionStateHandler
+func (_e *MockClient_Expecter) OnConnectionStateChange(handler interface{}) *MockClient_OnConnectionStateChange_Call {
+	return &MockClient_OnConnectionStateChange_Call{Call: _e.mock.On("OnConnectionStateChange", handler)}
+}
+
+func (_c *MockClient_OnConnectionStateChange_Call) Run(run func(handler mqtt.ConnectionStateHandler)) *MockClient_OnConnectionStateChange_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(mqtt.ConnectionStateHandler))
+	})
+	return _c
+}
+
+func (_c *MockClient_OnConnectionStateChange_Call) Return() *MockClient_OnConnectionStateChange_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockClient_OnConnectionStateChange_Call) RunAndReturn(run func(mqtt.ConnectionStateHandler)) *MockClient_OnConnectionStateChange_Call {
+	_c.Run(run)
+	return _c
+}
+
 // Pass provides a mock function with no fields
 func (_m *MockClient) Pass() bool {
 	ret := _m.Called()
@@ -249,17 +327,24 @@ func (_c *MockClient_Pass_Call) RunAndReturn(run func() bool) *MockClient_Pass_C
 	return _c
 }
 
-// Publish provides a mock function with given fields: ctx, topic, message
-func (_m *MockClient) Publish(ctx context.Context, topic mqtt.Topic, message interface{}) error {
-	ret := _m.Called(ctx, topic, message)
+// Publish provides a mock function with given fields: ctx, topic, message, opts
+func (_m *MockClient) Publish(ctx context.Context, topic mqtt.Topic, message interface{}, opts ...mqtt.PublishOpt) error {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, topic, message)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Publish")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, mqtt.Topic, interface{}) error); ok {
-		r0 = rf(ctx, topic, message)
+	if rf, ok := ret.Get(0).(func(context.Context, mqtt.Topic, interface{}, ...mqtt.PublishOpt) error); ok {
+		r0 = rf(ctx, topic, message, opts...)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -276,13 +361,21 @@ type MockClient_Publish_Call struct {
 //   - ctx context.Context
 //   - topic mqtt.Topic
 //   - message interface{}
-func (_e *MockClient_Expecter) Publish(ctx interface{}, topic interface{}, message interface{}) *MockClient_Publish_Call {
-	return &MockClient_Publish_Call{Call: _e.mock.On("Publish", ctx, topic, message)}
+//   - opts ...mqtt.PublishOpt
+func (_e *MockClient_Expecter) Publish(ctx interface{}, topic interface{}, message interface{}, opts ...interface{}) *MockClient_Publish_Call {
+	return &MockClient_Publish_Call{Call: _e.mock.On("Publish",
+		append([]interface{}{ctx, topic, message}, opts...)...)}
 }
 
-func (_c *MockClient_Publish_Call) Run(run func(ctx context.Context, topic mqtt.Topic, message interface{})) *MockClient_Publish_Call {
+func (_c *MockClient_Publish_Call) Run(run func(ctx context.Context, topic mqtt.Topic, message interface{}, opts ...mqtt.PublishOpt)) *MockClient_Publish_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(mqtt.Topic), args[2].(interface{}))
+		variadicArgs := make([]mqtt.PublishOpt, len(args)-3)
+		for i, a := range args[3:] {
+			if a != nil {
+				variadicArgs[i] = a.(mqtt.PublishOpt)
+			}
+		}
+		run(args[0].(context.Context), args[1].(mqtt.Topic), args[2].(interface{}), variadicArgs...)
 	})
 	return _c
 }
@@ -292,22 +385,104 @@ func (_c *MockClient_Publish_Call) Return(_a0 error) *MockClient_Publish_Call {
 	return _c
 }
 
-func (_c *MockClient_Publish_Call) RunAndReturn(run func(context.Context, mqtt.Topic, interface{}) error) *MockClient_Publish_Call {
+func (_c *MockClient_Publish_Call) RunAndReturn(run func(context.Context, mqtt.Topic, interface{}, ...mqtt.PublishOpt) error) *MockClient_Publish_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PublishRPC provides a mock function with given fields: ctx, topic, message, opts
+func (_m *MockClient) PublishRPC(ctx context.Context, topic mqtt.Topic, message interface{}, opts ...mqtt.PublishOpt) ([]byte, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, topic, message)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PublishRPC")
+	}
+
+	var r0 []byte
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, mqtt.Topic, interface{}, ...mqtt.PublishOpt) ([]byte, error)); ok {
+		return rf(ctx, topic, message, opts...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, mqtt.Topic, interface{}, ...mqtt.PublishOpt) []byte); ok {
+		r0 = rf(ctx, topic, message, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, mqtt.Topic, interface{}, ...mqtt.PublishOpt) error); ok {
+		r1 = rf(ctx, topic, message, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockClient_PublishRPC_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PublishRPC'
+type MockClient_PublishRPC_Call struct {
+	*mock.Call
+}
+
+// PublishRPC is a helper method to define mock.On call
+//   - ctx context.Context
+//   - topic mqtt.Topic
+//   - message interface{}
+//   - opts ...mqtt.PublishOpt
+func (_e *MockClient_Expecter) PublishRPC(ctx interface{}, topic interface{}, message interface{}, opts ...interface{}) *MockClient_PublishRPC_Call {
+	return &MockClient_PublishRPC_Call{Call: _e.mock.On("PublishRPC",
+		append([]interface{}{ctx, topic, message}, opts...)...)}
+}
+
+func (_c *MockClient_PublishRPC_Call) Run(run func(ctx context.Context, topic mqtt.Topic, message interface{}, opts ...mqtt.PublishOpt)) *MockClient_PublishRPC_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]mqtt.PublishOpt, len(args)-3)
+		for i, a := range args[3:] {
+			if a != nil {
+				variadicArgs[i] = a.(mqtt.PublishOpt)
+			}
+		}
+		run(args[0].(context.Context), args[1].(mqtt.Topic), args[2].(interface{}), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *MockClient_PublishRPC_Call) Return(_a0 []byte, _a1 error) *MockClient_PublishRPC_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockClient_PublishRPC_Call) RunAndReturn(run func(context.Context, mqtt.Topic, interface{}, ...mqtt.PublishOpt) ([]byte, error)) *MockClient_PublishRPC_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// Subscribe provides a mock function with given fields: ctx, topic, handler
-func (_m *MockClient) Subscribe(ctx context.Context, topic mqtt.Topic, handler mqtt.Handler) error {
-	ret := _m.Called(ctx, topic, handler)
+// Subscribe provides a mock function with given fields: ctx, topic, handler, opts
+func (_m *MockClient) Subscribe(ctx context.Context, topic mqtt.Topic, handler mqtt.Handler, opts ...mqtt.SubscribeOpt) error {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, topic, handler)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Subscribe")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, mqtt.Topic, mqtt.Handler) error); ok {
-		r0 = rf(ctx, topic, handler)
+	if rf, ok := ret.Get(0).(func(context.Context, mqtt.Topic, mqtt.Handler, ...mqtt.SubscribeOpt) error); ok {
+		r0 = rf(ctx, topic, handler, opts...)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -324,13 +499,21 @@ type MockClient_Subscribe_Call struct {
 //   - ctx context.Context
 //   - topic mqtt.Topic
 //   - handler mqtt.Handler
-func (_e *MockClient_Expecter) Subscribe(ctx interface{}, topic interface{}, handler interface{}) *MockClient_Subscribe_Call {
-	return &MockClient_Subscribe_Call{Call: _e.mock.On("Subscribe", ctx, topic, handler)}
+//   - opts ...mqtt.SubscribeOpt
+func (_e *MockClient_Expecter) Subscribe(ctx interface{}, topic interface{}, handler interface{}, opts ...interface{}) *MockClient_Subscribe_Call {
+	return &MockClient_Subscribe_Call{Call: _e.mock.On("Subscribe",
+		append([]interface{}{ctx, topic, handler}, opts...)...)}
 }
 
-func (_c *MockClient_Subscribe_Call) Run(run func(ctx context.Context, topic mqtt.Topic, handler mqtt.Handler)) *MockClient_Subscribe_Call {
+func (_c *MockClient_Subscribe_Call) Run(run func(ctx context.Context, topic mqtt.Topic, handler mqtt.Handler, opts ...mqtt.SubscribeOpt)) *MockClient_Subscribe_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(mqtt.Topic), args[2].(mqtt.Handler))
+		variadicArgs := make([]mqtt.SubscribeOpt, len(args)-3)
+		for i, a := range args[3:] {
+			if a != nil {
+				variadicArgs[i] = a.(mqtt.SubscribeOpt)
+			}
+		}
+		run(args[0].(context.Context), args[1].(mqtt.Topic), args[2].(mqtt.Handler), variadicArgs...)
 	})
 	return _c
 }
@@ -340,14 +523,148 @@ func (_c *MockClient_Subscribe_Call) Return(_a0 error) *MockClient_Subscribe_Cal
 	return _c
 }
 
-func (_c *MockClient_Subscribe_Call) RunAndReturn(run func(context.Context, mqtt.Topic, mqtt.Handler) error) *MockClient_Subscribe_Call {
+func (_c *MockClient_Subscribe_Call) RunAndReturn(run func(context.Context, mqtt.Topic, mqtt.Handler, ...mqtt.SubscribeOpt) error) *MockClient_Subscribe_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SubscribeRPC provides a mock function with given fields: ctx, topic, handler, opts
+func (_m *MockClient) SubscribeRPC(ctx context.Context, topic mqtt.Topic, handler mqtt.RequestHandler, opts ...mqtt.SubscribeOpt) error {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, topic, handler)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SubscribeRPC")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, mqtt.Topic, mqtt.RequestHandler, ...mqtt.SubscribeOpt) error); ok {
+		r0 = rf(ctx, topic, handler, opts...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockClient_SubscribeRPC_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SubscribeRPC'
+type MockClient_SubscribeRPC_Call struct {
+	*mock.Call
+}
+
+// SubscribeRPC is a helper method to define mock.On call
+//   - ctx context.Context
+//   - topic mqtt.Topic
+//   - handler mqtt.RequestHandler
+//   - opts ...mqtt.SubscribeOpt
+func (_e *MockClient_Expecter) SubscribeRPC(ctx interface{}, topic interface{}, handler interface{}, opts ...interface{}) *MockClient_SubscribeRPC_Call {
+	return &MockClient_SubscribeRPC_Call{Call: _e.mock.On("SubscribeRPC",
+		append([]interface{}{ctx, topic, handler}, opts...)...)}
+}
+
+func (_c *MockClient_SubscribeRPC_Call) Run(run func(ctx context.Context, topic mqtt.Topic, handler mqtt.RequestHandler, opts ...mqtt.SubscribeOpt)) *MockClient_SubscribeRPC_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]mqtt.SubscribeOpt, len(args)-3)
+		for i, a := range args[3:] {
+			if a != nil {
+				variadicArgs[i] = a.(mqtt.SubscribeOpt)
+			}
+		}
+		run(args[0].(context.Context), args[1].(mqtt.Topic), args[2].(mqtt.RequestHandler), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *MockClient_SubscribeRPC_Call) Return(_a0 error) *MockClient_SubscribeRPC_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockClient_SubscribeRPC_Call) RunAndReturn(run func(context.Context, mqtt.Topic, mqtt.RequestHandler, ...mqtt.SubscribeOpt) error) *MockClient_SubscribeRPC_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// SubscribeWithId provides a mock function with given fields: ctx, topic, handler
-func (_m *MockClient) SubscribeWithId(ctx context.Context, topic mqtt.Topic, handler mqtt.Handler) {
-	_m.Called(ctx, topic, handler)
+// SubscribeShared provides a mock function with given fields: ctx, group, topic, handler, opts
+func (_m *MockClient) SubscribeShared(ctx context.Context, group string, topic mqtt.Topic, handler mqtt.Handler, opts ...mqtt.SubscribeOpt) error {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, group, topic, handler)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SubscribeShared")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, mqtt.Topic, mqtt.Handler, ...mqtt.SubscribeOpt) error); ok {
+		r0 = rf(ctx, group, topic, handler, opts...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockClient_SubscribeShared_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SubscribeShared'
+type MockClient_SubscribeShared_Call struct {
+	*mock.Call
+}
+
+// SubscribeShared is a helper method to define mock.On call
+//   - ctx context.Context
+//   - group string
+//   - topic mqtt.Topic
+//   - handler mqtt.Handler
+//   - opts ...mqtt.SubscribeOpt
+func (_e *MockClient_Expecter) SubscribeShared(ctx interface{}, group interface{}, topic interface{}, handler interface{}, opts ...interface{}) *MockClient_SubscribeShared_Call {
+	return &MockClient_SubscribeShared_Call{Call: _e.mock.On("SubscribeShared",
+		append([]interface{}{ctx, group, topic, handler}, opts...)...)}
+}
+
+func (_c *MockClient_SubscribeShared_Call) Run(run func(ctx context.Context, group string, topic mqtt.Topic, handler mqtt.Handler, opts ...mqtt.SubscribeOpt)) *MockClient_SubscribeShared_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]mqtt.SubscribeOpt, len(args)-4)
+		for i, a := range args[4:] {
+			if a != nil {
+				variadicArgs[i] = a.(mqtt.SubscribeOpt)
+			}
+		}
+		run(args[0].(context.Context), args[1].(string), args[2].(mqtt.Topic), args[3].(mqtt.Handler), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *MockClient_SubscribeShared_Call) Return(_a0 error) *MockClient_SubscribeShared_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockClient_SubscribeShared_Call) RunAndReturn(run func(context.Context, string, mqtt.Topic, mqtt.Handler, ...mqtt.SubscribeOpt) error) *MockClient_SubscribeShared_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SubscribeWithId provides a mock function with given fields: ctx, topic, handler, opts
+func (_m *MockClient) SubscribeWithId(ctx context.Context, topic mqtt.Topic, handler mqtt.Handler, opts ...mqtt.SubscribeOpt) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, topic, handler)
+	_ca = append(_ca, _va...)
+	_m.Called(_ca...)
 }
 
 // MockClient_SubscribeWithId_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SubscribeWithId'
@@ -359,13 +676,21 @@ type MockClient_SubscribeWithId_Call struct {
 //   - ctx context.Context
 //   - topic mqtt.Topic
 //   - handler mqtt.Handler
-func (_e *MockClient_Expecter) SubscribeWithId(ctx interface{}, topic interface{}, handler interface{}) *MockClient_SubscribeWithId_Call {
-	return &MockClient_SubscribeWithId_Call{Call: _e.mock.On("SubscribeWithId", ctx, topic, handler)}
+//   - opts ...mqtt.SubscribeOpt
+func (_e *MockClient_Expecter) SubscribeWithId(ctx interface{}, topic interface{}, handler interface{}, opts ...interface{}) *MockClient_SubscribeWithId_Call {
+	return &MockClient_SubscribeWithId_Call{Call: _e.mock.On("SubscribeWithId",
+		append([]interface{}{ctx, topic, handler}, opts...)...)}
 }
 
-func (_c *MockClient_SubscribeWithId_Call) Run(run func(ctx context.Context, topic mqtt.Topic, handler mqtt.Handler)) *MockClient_SubscribeWithId_Call {
+func (_c *MockClient_SubscribeWithId_Call) Run(run func(ctx context.Context, topic mqtt.Topic, handler mqtt.Handler, opts ...mqtt.SubscribeOpt)) *MockClient_SubscribeWithId_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(mqtt.Topic), args[2].(mqtt.Handler))
+		variadicArgs := make([]mqtt.SubscribeOpt, len(args)-3)
+		for i, a := range args[3:] {
+			if a != nil {
+				variadicArgs[i] = a.(mqtt.SubscribeOpt)
+			}
+		}
+		run(args[0].(context.Context), args[1].(mqtt.Topic), args[2].(mqtt.Handler), variadicArgs...)
 	})
 	return _c
 }
@@ -375,11 +700,58 @@ func (_c *MockClient_SubscribeWithId_Call) Return() *MockClient_SubscribeWithId_
 	return _c
 }
 
-func (_c *MockClient_SubscribeWithId_Call) RunAndReturn(run func(context.Context, mqtt.Topic, mqtt.Handler)) *MockClient_SubscribeWithId_Call {
+func (_c *MockClient_SubscribeWithId_Call) RunAndReturn(run func(context.Context, mqtt.Topic, mqtt.Handler, ...mqtt.SubscribeOpt)) *MockClient_SubscribeWithId_Call {
 	_c.Run(run)
 	return _c
 }
 
+// Subscriptions provides a mock function with no fields
+func (_m *MockClient) Subscriptions() []mqtt.SubscriptionInfo {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Subscriptions")
+	}
+
+	var r0 []mqtt.SubscriptionInfo
+	if rf, ok := ret.Get(0).(func() []mqtt.SubscriptionInfo); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]mqtt.SubscriptionInfo)
+		}
+	}
+
+	return r0
+}
+
+// MockClient_Subscriptions_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Subscriptions'
+type MockClient_Subscriptions_Call struct {
+	*mock.Call
+}
+
+// Subscriptions is a helper method to define mock.On call
+func (_e *MockClient_Expecter) Subscriptions() *MockClient_Subscriptions_Call {
+	return &MockClient_Subscriptions_Call{Call: _e.mock.On("Subscriptions")}
+}
+
+func (_c *MockClient_Subscriptions_Call) Run(run func()) *MockClient_Subscriptions_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockClient_Subscriptions_Call) Return(_a0 []mqtt.SubscriptionInfo) *MockClient_Subscriptions_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockClient_Subscriptions_Call) RunAndReturn(run func() []mqtt.SubscriptionInfo) *MockClient_Subscriptions_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockClient creates a new instance of MockClient. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockClient(t interface {
@@ -0,0 +1,227 @@
+// Code generated by mockery v2.51.0. DO NOT EDIT.
+
+package mqtt
+
+import mock "github.com/stretchr/testify/mock"
+
+// MockCodec is an autogenerated mock type for the Codec type
+type MockCodec struct {
+	mock.Mock
+}
+
+type MockCodec_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockCodec) EXPECT() *MockCodec_Expecter {
+	return &MockCodec_Expecter{mock: &_m.Mock}
+}
+
+// ContentType provides a mock function with no fields
+func (_m *MockCodec) ContentType() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ContentType")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// MockCodec_ContentType_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ContentType'
+type MockCodec_ContentType_Call struct {
+	*mock.Call
+}
+
+// ContentType is a helper method to define mock.On call
+func (_e *MockCodec_Expecter) ContentType() *MockCodec_ContentType_Call {
+	return &MockCodec_ContentType_Call{Call: _e.mock.On("ContentType")}
+}
+
+func (_c *MockCodec_ContentType_Call) Run(run func()) *MockCodec_ContentType_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockCodec_ContentType_Call) Return(_a0 string) *MockCodec_ContentType_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockCodec_ContentType_Call) RunAndReturn(run func() string) *MockCodec_ContentType_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Decode provides a mock function with given fields: payload, target
+func (_m *MockCodec) Decode(payload []byte, target interface{}) error {
+	ret := _m.Called(payload, target)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Decode")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]byte, interface{}) error); ok {
+		r0 = rf(payload, target)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockCodec_Decode_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Decode'
+type MockCodec_Decode_Call struct {
+	*mock.Call
+}
+
+// Decode is a helper method to define mock.On call
+//   - payload []byte
+//   - target interface{}
+func (_e *MockCodec_Expecter) Decode(payload interface{}, target interface{}) *MockCodec_Decode_Call {
+	return &MockCodec_Decode_Call{Call: _e.mock.On("Decode", payload, target)}
+}
+
+func (_c *MockCodec_Decode_Call) Run(run func(payload []byte, target interface{})) *MockCodec_Decode_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]byte), args[1].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockCodec_Decode_Call) Return(_a0 error) *MockCodec_Decode_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockCodec_Decode_Call) RunAndReturn(run func([]byte, interface{}) error) *MockCodec_Decode_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Encode provides a mock function with given fields: message
+func (_m *MockCodec) Encode(message interface{}) ([]byte, error) {
+	ret := _m.Called(message)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Encode")
+	}
+
+	var r0 []byte
+	var r1 error
+	if rf, ok := ret.Get(0).(func(interface{}) ([]byte, error)); ok {
+		return rf(message)
+	}
+	if rf, ok := ret.Get(0).(func(interface{}) []byte); ok {
+		r0 = rf(message)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(interface{}) error); ok {
+		r1 = rf(message)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockCodec_Encode_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Encode'
+type MockCodec_Encode_Call struct {
+	*mock.Call
+}
+
+// Encode is a helper method to define mock.On call
+//   - message interface{}
+func (_e *MockCodec_Expecter) Encode(message interface{}) *MockCodec_Encode_Call {
+	return &MockCodec_Encode_Call{Call: _e.mock.On("Encode", message)}
+}
+
+func (_c *MockCodec_Encode_Call) Run(run func(message interface{})) *MockCodec_Encode_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockCodec_Encode_Call) Return(_a0 []byte, _a1 error) *MockCodec_Encode_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockCodec_Encode_Call) RunAndReturn(run func(interface{}) ([]byte, error)) *MockCodec_Encode_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Name provides a mock function with no fields
+func (_m *MockCodec) Name() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Name")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// MockCodec_Name_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Name'
+type MockCodec_Name_Call struct {
+	*mock.Call
+}
+
+// Name is a helper method to define mock.On call
+func (_e *MockCodec_Expecter) Name() *MockCodec_Name_Call {
+	return &MockCodec_Name_Call{Call: _e.mock.On("Name")}
+}
+
+func (_c *MockCodec_Name_Call) Run(run func()) *MockCodec_Name_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockCodec_Name_Call) Return(_a0 string) *MockCodec_Name_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockCodec_Name_Call) RunAndReturn(run func() string) *MockCodec_Name_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockCodec creates a new instance of MockCodec. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockCodec(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockCodec {
+	mock := &MockCodec{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
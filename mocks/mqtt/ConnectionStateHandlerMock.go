@@ -0,0 +1,69 @@
+// Code generated by mockery v2.51.0. DO NOT EDIT.
+
+package mqtt
+
+import (
+	mock "github.com/stretchr/testify/mock"
+	mqtt "github.com/xBlaz3kx/DevX/mqtt"
+)
+
+// MockConnectionStateHandler is an autogenerated mock type for the ConnectionStateHandler type
+type MockConnectionStateHandler struct {
+	mock.Mock
+}
+
+type MockConnectionStateHandler_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockConnectionStateHandler) EXPECT() *MockConnectionStateHandler_Expecter {
+	return &MockConnectionStateHandler_Expecter{mock: &_m.Mock}
+}
+
+// Execute provides a mock function with given fields: state, err
+func (_m *MockConnectionStateHandler) Execute(state mqtt.ConnectionState, err error) {
+	_m.Called(state, err)
+}
+
+// MockConnectionStateHandler_Execute_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Execute'
+type MockConnectionStateHandler_Execute_Call struct {
+	*mock.Call
+}
+
+// Execute is a helper method to define mock.On call
+//   - state mqtt.ConnectionState
+//   - err error
+func (_e *MockConnectionStateHandler_Expecter) Execute(state interface{}, err interface{}) *MockConnectionStateHandler_Execute_Call {
+	return &MockConnectionStateHandler_Execute_Call{Call: _e.mock.On("Execute", state, err)}
+}
+
+func (_c *MockConnectionStateHandler_Execute_Call) Run(run func(state mqtt.ConnectionState, err error)) *MockConnectionStateHandler_Execute_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(mqtt.ConnectionState), args[1].(error))
+	})
+	return _c
+}
+
+func (_c *MockConnectionStateHandler_Execute_Call) Return() *MockConnectionStateHandler_Execute_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockConnectionStateHandler_Execute_Call) RunAndReturn(run func(mqtt.ConnectionState, error)) *MockConnectionStateHandler_Execute_Call {
+	_c.Run(run)
+	return _c
+}
+
+// NewMockConnectionStateHandler creates a new instance of MockConnectionStateHandler. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockConnectionStateHandler(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockConnectionStateHandler {
+	mock := &MockConnectionStateHandler{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
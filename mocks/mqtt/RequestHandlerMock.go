@@ -0,0 +1,96 @@
+// Code generated by mockery v2.51.0. DO NOT EDIT.
+
+package mqtt
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockRequestHandler is an autogenerated mock type for the RequestHandler type
+type MockRequestHandler struct {
+	mock.Mock
+}
+
+type MockRequestHandler_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockRequestHandler) EXPECT() *MockRequestHandler_Expecter {
+	return &MockRequestHandler_Expecter{mock: &_m.Mock}
+}
+
+// Execute provides a mock function with given fields: ctx, topicIds, request
+func (_m *MockRequestHandler) Execute(ctx context.Context, topicIds []string, request interface{}) (interface{}, error) {
+	ret := _m.Called(ctx, topicIds, request)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Execute")
+	}
+
+	var r0 interface{}
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string, interface{}) (interface{}, error)); ok {
+		return rf(ctx, topicIds, request)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string, interface{}) interface{}); ok {
+		r0 = rf(ctx, topicIds, request)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(interface{})
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string, interface{}) error); ok {
+		r1 = rf(ctx, topicIds, request)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockRequestHandler_Execute_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Execute'
+type MockRequestHandler_Execute_Call struct {
+	*mock.Call
+}
+
+// Execute is a helper method to define mock.On call
+//   - ctx context.Context
+//   - topicIds []string
+//   - request interface{}
+func (_e *MockRequestHandler_Expecter) Execute(ctx interface{}, topicIds interface{}, request interface{}) *MockRequestHandler_Execute_Call {
+	return &MockRequestHandler_Execute_Call{Call: _e.mock.On("Execute", ctx, topicIds, request)}
+}
+
+func (_c *MockRequestHandler_Execute_Call) Run(run func(ctx context.Context, topicIds []string, request interface{})) *MockRequestHandler_Execute_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]string), args[2].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockRequestHandler_Execute_Call) Return(_a0 interface{}, _a1 error) *MockRequestHandler_Execute_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockRequestHandler_Execute_Call) RunAndReturn(run func(context.Context, []string, interface{}) (interface{}, error)) *MockRequestHandler_Execute_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockRequestHandler creates a new instance of MockRequestHandler. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockRequestHandler(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockRequestHandler {
+	mock := &MockRequestHandler{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
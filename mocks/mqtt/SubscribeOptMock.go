@@ -0,0 +1,68 @@
+// Code generated by mockery v2.51.0. DO NOT EDIT.
+
+package mqtt
+
+import (
+	mock "github.com/stretchr/testify/mock"
+	mqtt "github.com/xBlaz3kx/DevX/mqtt"
+)
+
+// MockSubscribeOpt is an autogenerated mock type for the SubscribeOpt type
+type MockSubscribeOpt struct {
+	mock.Mock
+}
+
+type MockSubscribeOpt_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockSubscribeOpt) EXPECT() *MockSubscribeOpt_Expecter {
+	return &MockSubscribeOpt_Expecter{mock: &_m.Mock}
+}
+
+// Execute provides a mock function with given fields: _a0
+func (_m *MockSubscribeOpt) Execute(_a0 *mqtt.SubscribeOptions) {
+	_m.Called(_a0)
+}
+
+// MockSubscribeOpt_Execute_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Execute'
+type MockSubscribeOpt_Execute_Call struct {
+	*mock.Call
+}
+
+// Execute is a helper method to define mock.On call
+//   - _a0 *mqtt.SubscribeOptions
+func (_e *MockSubscribeOpt_Expecter) Execute(_a0 interface{}) *MockSubscribeOpt_Execute_Call {
+	return &MockSubscribeOpt_Execute_Call{Call: _e.mock.On("Execute", _a0)}
+}
+
+func (_c *MockSubscribeOpt_Execute_Call) Run(run func(_a0 *mqtt.SubscribeOptions)) *MockSubscribeOpt_Execute_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*mqtt.SubscribeOptions))
+	})
+	return _c
+}
+
+func (_c *MockSubscribeOpt_Execute_Call) Return() *MockSubscribeOpt_Execute_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockSubscribeOpt_Execute_Call) RunAndReturn(run func(*mqtt.SubscribeOptions)) *MockSubscribeOpt_Execute_Call {
+	_c.Run(run)
+	return _c
+}
+
+// NewMockSubscribeOpt creates a new instance of MockSubscribeOpt. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockSubscribeOpt(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockSubscribeOpt {
+	mock := &MockSubscribeOpt{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,72 @@
+// Code generated by mockery v2.51.0. DO NOT EDIT.
+
+package mqtt
+
+import (
+	mock "github.com/stretchr/testify/mock"
+	mqtt "github.com/xBlaz3kx/DevX/mqtt"
+)
+
+// MockTemplateHandler is an autogenerated mock type for the TemplateHandler type
+type MockTemplateHandler struct {
+	mock.Mock
+}
+
+type MockTemplateHandler_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockTemplateHandler) EXPECT() *MockTemplateHandler_Expecter {
+	return &MockTemplateHandler_Expecter{mock: &_m.Mock}
+}
+
+// Execute provides a mock function with given fields: client, params, payloadId, payload, err
+func (_m *MockTemplateHandler) Execute(client mqtt.Client, params mqtt.TopicParams, payloadId uint16, payload interface{}, err error) {
+	_m.Called(client, params, payloadId, payload, err)
+}
+
+// MockTemplateHandler_Execute_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Execute'
+type MockTemplateHandler_Execute_Call struct {
+	*mock.Call
+}
+
+// Execute is a helper method to define mock.On call
+//   - client mqtt.Client
+//   - params mqtt.TopicParams
+//   - payloadId uint16
+//   - payload interface{}
+//   - err error
+func (_e *MockTemplateHandler_Expecter) Execute(client interface{}, params interface{}, payloadId interface{}, payload interface{}, err interface{}) *MockTemplateHandler_Execute_Call {
+	return &MockTemplateHandler_Execute_Call{Call: _e.mock.On("Execute", client, params, payloadId, payload, err)}
+}
+
+func (_c *MockTemplateHandler_Execute_Call) Run(run func(client mqtt.Client, params mqtt.TopicParams, payloadId uint16, payload interface{}, err error)) *MockTemplateHandler_Execute_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(mqtt.Client), args[1].(mqtt.TopicParams), args[2].(uint16), args[3].(interface{}), args[4].(error))
+	})
+	return _c
+}
+
+func (_c *MockTemplateHandler_Execute_Call) Return() *MockTemplateHandler_Execute_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockTemplateHandler_Execute_Call) RunAndReturn(run func(mqtt.Client, mqtt.TopicParams, uint16, interface{}, error)) *MockTemplateHandler_Execute_Call {
+	_c.Run(run)
+	return _c
+}
+
+// NewMockTemplateHandler creates a new instance of MockTemplateHandler. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockTemplateHandler(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockTemplateHandler {
+	mock := &MockTemplateHandler{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,72 @@
+// Code generated by mockery v2.51.0. DO NOT EDIT.
+
+package mqtt
+
+import (
+	mock "github.com/stretchr/testify/mock"
+	mqtt "github.com/xBlaz3kx/DevX/mqtt"
+)
+
+// MockTypedHandler is an autogenerated mock type for the TypedHandler type
+type MockTypedHandler[T interface{}] struct {
+	mock.Mock
+}
+
+type MockTypedHandler_Expecter[T interface{}] struct {
+	mock *mock.Mock
+}
+
+func (_m *MockTypedHandler[T]) EXPECT() *MockTypedHandler_Expecter[T] {
+	return &MockTypedHandler_Expecter[T]{mock: &_m.Mock}
+}
+
+// Execute provides a mock function with given fields: client, topicIds, payloadId, payload, err
+func (_m *MockTypedHandler[T]) Execute(client mqtt.Client, topicIds []string, payloadId uint16, payload T, err error) {
+	_m.Called(client, topicIds, payloadId, payload, err)
+}
+
+// MockTypedHandler_Execute_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Execute'
+type MockTypedHandler_Execute_Call[T interface{}] struct {
+	*mock.Call
+}
+
+// Execute is a helper method to define mock.On call
+//   - client mqtt.Client
+//   - topicIds []string
+//   - payloadId uint16
+//   - payload T
+//   - err error
+func (_e *MockTypedHandler_Expecter[T]) Execute(client interface{}, topicIds interface{}, payloadId interface{}, payload interface{}, err interface{}) *MockTypedHandler_Execute_Call[T] {
+	return &MockTypedHandler_Execute_Call[T]{Call: _e.mock.On("Execute", client, topicIds, payloadId, payload, err)}
+}
+
+func (_c *MockTypedHandler_Execute_Call[T]) Run(run func(client mqtt.Client, topicIds []string, payloadId uint16, payload T, err error)) *MockTypedHandler_Execute_Call[T] {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(mqtt.Client), args[1].([]string), args[2].(uint16), args[3].(T), args[4].(error))
+	})
+	return _c
+}
+
+func (_c *MockTypedHandler_Execute_Call[T]) Return() *MockTypedHandler_Execute_Call[T] {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockTypedHandler_Execute_Call[T]) RunAndReturn(run func(mqtt.Client, []string, uint16, T, error)) *MockTypedHandler_Execute_Call[T] {
+	_c.Run(run)
+	return _c
+}
+
+// NewMockTypedHandler creates a new instance of MockTypedHandler. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockTypedHandler[T interface{}](t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockTypedHandler[T] {
+	mock := &MockTypedHandler[T]{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
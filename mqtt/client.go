@@ -2,6 +2,7 @@ package mqtt
 
 import (
 	"context"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/tavsec/gin-healthcheck/checks"
@@ -22,18 +23,72 @@ type Configuration struct {
 	Password string  `yaml:"password"`
 	ClientId string  `validate:"required" yaml:"clientId"`
 	TLS      tls.TLS `validate:"required" yaml:"tls"`
+
+	// KeepAlive is the maximum interval between the packets sent to the broker. Defaults to 30s.
+	KeepAlive time.Duration `json:"keepAlive,omitempty" yaml:"keepAlive"`
+
+	// ConnectTimeout limits a single connection attempt. Defaults to 10s.
+	ConnectTimeout time.Duration `json:"connectTimeout,omitempty" yaml:"connectTimeout"`
+
+	// PersistentSession disables the clean start (clean session in v3), so the broker resumes the existing session
+	// of the client id with its subscriptions and queued messages. Requires a client id that is stable across restarts.
+	PersistentSession bool `json:"persistentSession,omitempty" yaml:"persistentSession"`
+
+	// SessionExpiry is how long the broker keeps the session after the connection is closed. Only used by v5,
+	// where the session ends with the connection if it is not set.
+	SessionExpiry time.Duration `json:"sessionExpiry,omitempty" yaml:"sessionExpiry"`
+
+	// ReconnectBackoff is the delay between the reconnection attempts
+	ReconnectBackoff ReconnectBackoff `json:"reconnectBackoff,omitempty" yaml:"reconnectBackoff"`
+
+	// OfflineQueue persists the messages published while the client is disconnected and publishes them in order
+	// after reconnecting. Disabled by default.
+	OfflineQueue OfflineQueue `json:"offlineQueue,omitempty" yaml:"offlineQueue"`
+
+	// Codec encodes the published messages and decodes the received payloads: raw, json, cbor or protobuf.
+	// Defaults to json.
+	Codec string `json:"codec,omitempty" yaml:"codec"`
+
+	// TraceEnvelope wraps the payloads of the v3 messages in a JSON envelope with the trace context, as MQTT v3 has
+	// no user properties. The publishers and subscribers of the topics must all enable it. Ignored by v5.
+	TraceEnvelope bool `json:"traceEnvelope,omitempty" yaml:"traceEnvelope"`
+
+	// LastWill is published by the broker when the connection is lost without a clean disconnect
+	LastWill *LastWill `json:"lastWill,omitempty" yaml:"lastWill"`
+
+	// BirthMessage is published after every (re)connection
+	BirthMessage *PresenceMessage `json:"birthMessage,omitempty" yaml:"birthMessage"`
+
+	// DeathMessage is published before a clean Disconnect, as the broker discards the last will of a clean disconnect
+	DeathMessage *PresenceMessage `json:"deathMessage,omitempty" yaml:"deathMessage"`
 }
 
 type Handler func(client Client, topicIds []string, payloadId uint16, payload interface{}, err error)
 
 // Client is an interface wrapper for a simple MQTT client.
 type Client interface {
+	// Connect connects to the broker and returns the error of the connection attempt. The client keeps reconnecting
+	// after the connection is lost.
 	Connect(ctx context.Context) error
+	// Disconnect waits for the messages in flight until the context is done, publishes the death message and
+	// disconnects cleanly.
 	Disconnect(ctx context.Context) error
-	Publish(ctx context.Context, topic Topic, message interface{}) error
-	// PublishRPC(ctx context.Context, topic Topic, message interface{}) error
-	SubscribeWithId(ctx context.Context, topic Topic, handler Handler)
-	Subscribe(ctx context.Context, topic Topic, handler Handler) error
+	Publish(ctx context.Context, topic Topic, message interface{}, opts ...PublishOpt) error
+	// PublishRPC publishes a request and waits for the response. Only supported by MQTT v5.
+	PublishRPC(ctx context.Context, topic Topic, message interface{}, opts ...PublishOpt) ([]byte, error)
+	// SubscribeRPC subscribes to requests and publishes the responses of the handler. Only supported by MQTT v5.
+	SubscribeRPC(ctx context.Context, topic Topic, handler RequestHandler, opts ...SubscribeOpt) error
+	SubscribeWithId(ctx context.Context, topic Topic, handler Handler, opts ...SubscribeOpt)
+	Subscribe(ctx context.Context, topic Topic, handler Handler, opts ...SubscribeOpt) error
+	// SubscribeShared subscribes to a topic as a member of a shared subscription group, so each message is delivered to
+	// only one member of the group.
+	SubscribeShared(ctx context.Context, group string, topic Topic, handler Handler, opts ...SubscribeOpt) error
+	// Subscriptions returns the state of the subscriptions, which are restored after every reconnection
+	Subscriptions() []SubscriptionInfo
+	// ConnectionState returns the current state of the connection
+	ConnectionState() ConnectionState
+	// OnConnectionStateChange registers a handler called whenever the connection state changes
+	OnConnectionStateChange(handler ConnectionStateHandler)
 	GetId() string
 	checks.Check
 }
@@ -0,0 +1,205 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	CodecRaw      = "raw"
+	CodecJSON     = "json"
+	CodecCBOR     = "cbor"
+	CodecProtobuf = "protobuf"
+)
+
+var (
+	ErrUnknownCodec    = errors.New("unknown codec")
+	ErrUnsupportedType = errors.New("type is not supported by the codec")
+)
+
+// Codec encodes the published messages and decodes the received payloads.
+// Decoding into *interface{} is used for the untyped handlers.
+type Codec interface {
+	// Name is the name of the codec used in the configuration
+	Name() string
+	// ContentType is set as the content type of the MQTT v5 messages and is used to choose the codec of the received messages
+	ContentType() string
+	Encode(message interface{}) ([]byte, error)
+	Decode(payload []byte, target interface{}) error
+}
+
+var (
+	RawCodec      Codec = rawCodec{}
+	JSONCodec     Codec = jsonCodec{}
+	CBORCodec     Codec = newCborCodec()
+	ProtobufCodec Codec = protobufCodec{}
+)
+
+// codecs are the built-in codecs
+var codecs = []Codec{RawCodec, JSONCodec, CBORCodec, ProtobufCodec}
+
+// CodecByName returns the built-in codec with the name. An empty name returns the JSON codec.
+func CodecByName(name string) (Codec, error) {
+	if name == "" {
+		return JSONCodec, nil
+	}
+
+	for _, codec := range codecs {
+		if codec.Name() == name {
+			return codec, nil
+		}
+	}
+
+	return nil, errors.Wrap(ErrUnknownCodec, name)
+}
+
+// codecByContentType returns the codec of the content type from the preferred codecs or the built-in codecs
+func codecByContentType(contentType string, preferred ...Codec) (Codec, bool) {
+	if contentType == "" {
+		return nil, false
+	}
+
+	for _, codec := range append(preferred, codecs...) {
+		if codec != nil && codec.ContentType() == contentType {
+			return codec, true
+		}
+	}
+
+	return nil, false
+}
+
+// rawCodec passes the payload as bytes
+type rawCodec struct{}
+
+func (rawCodec) Name() string {
+	return CodecRaw
+}
+
+func (rawCodec) ContentType() string {
+	return "application/octet-stream"
+}
+
+func (rawCodec) Encode(message interface{}) ([]byte, error) {
+	switch message := message.(type) {
+	case []byte:
+		return message, nil
+	case string:
+		return []byte(message), nil
+	default:
+		return nil, errors.Wrapf(ErrUnsupportedType, "raw codec cannot encode %T", message)
+	}
+}
+
+func (rawCodec) Decode(payload []byte, target interface{}) error {
+	switch target := target.(type) {
+	case *[]byte:
+		*target = payload
+	case *string:
+		*target = string(payload)
+	case *interface{}:
+		*target = payload
+	default:
+		return errors.Wrapf(ErrUnsupportedType, "raw codec cannot decode into %T", target)
+	}
+
+	return nil
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return CodecJSON
+}
+
+func (jsonCodec) ContentType() string {
+	return "application/json"
+}
+
+func (jsonCodec) Encode(message interface{}) ([]byte, error) {
+	return json.Marshal(message)
+}
+
+func (jsonCodec) Decode(payload []byte, target interface{}) error {
+	return json.Unmarshal(payload, target)
+}
+
+type cborCodec struct {
+	decMode cbor.DecMode
+}
+
+func newCborCodec() cborCodec {
+	// Decode maps like JSON, so the untyped handlers receive the same types for both codecs
+	decMode, err := cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]interface{}(nil))}.DecMode()
+	if err != nil {
+		panic(fmt.Sprintf("invalid cbor decoding options: %v", err))
+	}
+
+	return cborCodec{decMode: decMode}
+}
+
+func (cborCodec) Name() string {
+	return CodecCBOR
+}
+
+func (cborCodec) ContentType() string {
+	return "application/cbor"
+}
+
+func (cborCodec) Encode(message interface{}) ([]byte, error) {
+	return cbor.Marshal(message)
+}
+
+func (c cborCodec) Decode(payload []byte, target interface{}) error {
+	return c.decMode.Unmarshal(payload, target)
+}
+
+// protobufCodec encodes proto messages. Untyped handlers receive the payload as bytes, as the message type is unknown.
+type protobufCodec struct{}
+
+func (protobufCodec) Name() string {
+	return CodecProtobuf
+}
+
+func (protobufCodec) ContentType() string {
+	return "application/x-protobuf"
+}
+
+func (protobufCodec) Encode(message interface{}) ([]byte, error) {
+	protoMessage, ok := message.(proto.Message)
+	if !ok {
+		return nil, errors.Wrapf(ErrUnsupportedType, "protobuf codec cannot encode %T", message)
+	}
+
+	return proto.Marshal(protoMessage)
+}
+
+func (protobufCodec) Decode(payload []byte, target interface{}) error {
+	switch target := target.(type) {
+	case proto.Message:
+		return proto.Unmarshal(payload, target)
+	case *interface{}:
+		*target = payload
+		return nil
+	}
+
+	// Pointer to a message pointer, as decoded by the typed handlers
+	value := reflect.ValueOf(target)
+	if value.Kind() == reflect.Pointer && value.Elem().Kind() == reflect.Pointer {
+		message, ok := reflect.New(value.Elem().Type().Elem()).Interface().(proto.Message)
+		if ok {
+			if err := proto.Unmarshal(payload, message); err != nil {
+				return err
+			}
+
+			value.Elem().Set(reflect.ValueOf(message))
+			return nil
+		}
+	}
+
+	return errors.Wrapf(ErrUnsupportedType, "protobuf codec cannot decode into %T", target)
+}
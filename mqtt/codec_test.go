@@ -0,0 +1,149 @@
+package mqtt
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type deviceStatus struct {
+	Status string `json:"status" cbor:"status"`
+	Level  int    `json:"level" cbor:"level"`
+}
+
+func TestCodecByName(t *testing.T) {
+	codec, err := CodecByName("")
+	require.NoError(t, err)
+	assert.Equal(t, JSONCodec, codec)
+
+	for _, name := range []string{CodecRaw, CodecJSON, CodecCBOR, CodecProtobuf} {
+		codec, err = CodecByName(name)
+		require.NoError(t, err)
+		assert.Equal(t, name, codec.Name())
+	}
+
+	_, err = CodecByName("xml")
+	assert.ErrorIs(t, err, ErrUnknownCodec)
+}
+
+func TestCodecByContentType(t *testing.T) {
+	codec, ok := codecByContentType("application/cbor")
+	require.True(t, ok)
+	assert.Equal(t, CodecCBOR, codec.Name())
+
+	_, ok = codecByContentType("")
+	assert.False(t, ok)
+
+	_, ok = codecByContentType("application/xml", JSONCodec)
+	assert.False(t, ok)
+}
+
+func TestCodecs(t *testing.T) {
+	t.Run("Raw", func(t *testing.T) {
+		payload, err := RawCodec.Encode([]byte{0x01, 0x02})
+		require.NoError(t, err)
+
+		var data interface{}
+		require.NoError(t, RawCodec.Decode(payload, &data))
+		assert.Equal(t, []byte{0x01, 0x02}, data)
+
+		_, err = RawCodec.Encode(deviceStatus{})
+		assert.ErrorIs(t, err, ErrUnsupportedType)
+	})
+
+	for _, codec := range []Codec{JSONCodec, CBORCodec} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			payload, err := codec.Encode(deviceStatus{Status: "online", Level: 3})
+			require.NoError(t, err)
+
+			status := deviceStatus{}
+			require.NoError(t, codec.Decode(payload, &status))
+			assert.Equal(t, deviceStatus{Status: "online", Level: 3}, status)
+
+			// Untyped handlers receive maps with string keys
+			var data interface{}
+			require.NoError(t, codec.Decode(payload, &data))
+			assert.Contains(t, data, "status")
+		})
+	}
+
+	t.Run("Protobuf", func(t *testing.T) {
+		payload, err := ProtobufCodec.Encode(wrapperspb.String("online"))
+		require.NoError(t, err)
+
+		message := &wrapperspb.StringValue{}
+		require.NoError(t, ProtobufCodec.Decode(payload, message))
+		assert.Equal(t, "online", message.GetValue())
+
+		// Pointer to a message pointer, as used by the typed handlers
+		var target *wrapperspb.StringValue
+		require.NoError(t, ProtobufCodec.Decode(payload, &target))
+		assert.True(t, proto.Equal(wrapperspb.String("online"), target))
+
+		// The untyped handlers receive the bytes
+		var data interface{}
+		require.NoError(t, ProtobufCodec.Decode(payload, &data))
+		assert.Equal(t, payload, data)
+
+		_, err = ProtobufCodec.Encode(deviceStatus{})
+		assert.ErrorIs(t, err, ErrUnsupportedType)
+	})
+}
+
+func TestV5SubscribeTyped(t *testing.T) {
+	client := newTestV5Client(t)
+
+	var received []deviceStatus
+	var errs []error
+	err := SubscribeTyped(context.Background(), client, "devices/+/status", func(_ Client, _ []string, _ uint16, payload deviceStatus, err error) {
+		received = append(received, payload)
+		errs = append(errs, err)
+	})
+	require.NoError(t, err)
+
+	// The codec is chosen from the content type
+	cborPayload, err := CBORCodec.Encode(deviceStatus{Status: "offline", Level: 1})
+	require.NoError(t, err)
+
+	routeMessage(client, "devices/device1/status", `{"status":"online","level":2}`)
+	client.router.Route((&paho.Publish{
+		Topic:      "devices/device1/status",
+		Payload:    cborPayload,
+		Properties: &paho.PublishProperties{ContentType: CBORCodec.ContentType()},
+	}).Packet())
+
+	require.Len(t, received, 2)
+	assert.Equal(t, deviceStatus{Status: "online", Level: 2}, received[0])
+	assert.Equal(t, deviceStatus{Status: "offline", Level: 1}, received[1])
+	assert.Equal(t, []error{nil, nil}, errs)
+}
+
+func TestV5SubscribeWithCodec(t *testing.T) {
+	client := newTestV5Client(t)
+
+	var received []interface{}
+	client.SubscribeWithId(context.Background(), "devices/+/firmware", func(_ Client, _ []string, _ uint16, payload interface{}, err error) {
+		require.NoError(t, err)
+		received = append(received, payload)
+	}, WithSubscriptionCodec(RawCodec))
+
+	routeMessage(client, "devices/device1/firmware", "\x00\x01binary")
+
+	require.Len(t, received, 1)
+	assert.Equal(t, []byte("\x00\x01binary"), received[0])
+}
+
+func TestTypedHandlerUnexpectedPayload(t *testing.T) {
+	var receivedErr error
+	handler := newTypedHandler(func(_ Client, _ []string, _ uint16, payload deviceStatus, err error) {
+		receivedErr = err
+	})
+
+	handler(nil, nil, 0, map[string]interface{}{}, nil)
+	assert.ErrorIs(t, receivedErr, ErrUnsupportedType)
+}
@@ -0,0 +1,107 @@
+package mqtt
+
+import (
+	cryptoTls "crypto/tls"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrStableClientIdRequired is returned for a persistent session or an offline queue without a client id, as they
+// are resumed by the client id
+var ErrStableClientIdRequired = errors.New("a stable client id is required")
+
+const (
+	defaultKeepAlive             = 30 * time.Second
+	defaultConnectTimeout        = 10 * time.Second
+	defaultReconnectInitialDelay = time.Second
+	defaultReconnectMaxDelay     = 5 * time.Second
+)
+
+// ReconnectBackoff configures the delay between the reconnection attempts. The delay doubles after every failed attempt.
+type ReconnectBackoff struct {
+	// InitialDelay is the delay before the first reconnection attempt. Defaults to 1s.
+	// The v3 client ignores it, as it always starts reconnecting after 1s.
+	InitialDelay time.Duration `json:"initialDelay,omitempty" yaml:"initialDelay"`
+
+	// MaxDelay is the upper bound of the delay. Defaults to 5s.
+	MaxDelay time.Duration `json:"maxDelay,omitempty" yaml:"maxDelay"`
+}
+
+// delay returns the delay before the reconnection attempt. The first attempt is not delayed.
+func (b ReconnectBackoff) delay(attempt int) time.Duration {
+	if attempt <= 0 {
+		return 0
+	}
+
+	delay := b.InitialDelay
+	for i := 1; i < attempt && delay < b.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, b.MaxDelay)
+}
+
+// withDefaults returns the configuration with the defaults applied to the connection settings
+func (c Configuration) withDefaults() Configuration {
+	if c.KeepAlive <= 0 {
+		c.KeepAlive = defaultKeepAlive
+	}
+
+	if c.ConnectTimeout <= 0 {
+		c.ConnectTimeout = defaultConnectTimeout
+	}
+
+	if c.ReconnectBackoff.InitialDelay <= 0 {
+		c.ReconnectBackoff.InitialDelay = defaultReconnectInitialDelay
+	}
+
+	if c.ReconnectBackoff.MaxDelay <= 0 {
+		c.ReconnectBackoff.MaxDelay = defaultReconnectMaxDelay
+	}
+	c.ReconnectBackoff.MaxDelay = max(c.ReconnectBackoff.MaxDelay, c.ReconnectBackoff.InitialDelay)
+
+	return c
+}
+
+// validateSession checks that a persistent session has a client id to be resumed with
+func (c Configuration) validateSession() error {
+	if c.PersistentSession && strings.TrimSpace(c.ClientId) == "" {
+		return ErrStableClientIdRequired
+	}
+
+	return nil
+}
+
+// brokerUrl parses the broker address. If TLS is enabled, plain TCP and WebSocket schemes are upgraded to their
+// secure variants, as the clients select the transport by the scheme.
+func (c Configuration) brokerUrl() (*url.URL, error) {
+	brokerUrl, err := url.Parse(c.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.TLS.IsEnabled {
+		return brokerUrl, nil
+	}
+
+	switch strings.ToLower(brokerUrl.Scheme) {
+	case "mqtt", "tcp", "":
+		brokerUrl.Scheme = "mqtts"
+	case "ws":
+		brokerUrl.Scheme = "wss"
+	}
+
+	return brokerUrl, nil
+}
+
+// tlsConfig returns the TLS configuration, nil if TLS is disabled
+func (c Configuration) tlsConfig() (*cryptoTls.Config, error) {
+	if !c.TLS.IsEnabled {
+		return nil, nil
+	}
+
+	return c.TLS.ToTlsConfig()
+}
@@ -0,0 +1,175 @@
+package mqtt
+
+import (
+	"context"
+	"sync"
+)
+
+// ConnectionState is the state of the connection to the broker
+type ConnectionState string
+
+const (
+	// ConnectionStateDisconnected before Connect, after Disconnect or after a failed Connect
+	ConnectionStateDisconnected ConnectionState = "disconnected"
+	// ConnectionStateConnecting while Connect is establishing the first connection
+	ConnectionStateConnecting ConnectionState = "connecting"
+	// ConnectionStateConnected while the connection is up
+	ConnectionStateConnected ConnectionState = "connected"
+	// ConnectionStateConnectionLost when the connection was lost unexpectedly
+	ConnectionStateConnectionLost ConnectionState = "connection_lost"
+	// ConnectionStateReconnecting while the client is reconnecting after losing the connection
+	ConnectionStateReconnecting ConnectionState = "reconnecting"
+)
+
+// ConnectionStateHandler is called when the connection state changes, with the error that caused the change if any.
+// The handler must not block.
+type ConnectionStateHandler func(state ConnectionState, err error)
+
+// connectionState tracks the state of the connection and notifies the handlers about the changes
+type connectionState struct {
+	mu        sync.Mutex
+	state     ConnectionState
+	lastError error
+	handlers  []ConnectionStateHandler
+	// changed is closed and replaced on every change
+	changed chan struct{}
+}
+
+func newConnectionState() *connectionState {
+	return &connectionState{state: ConnectionStateDisconnected, changed: make(chan struct{})}
+}
+
+func (s *connectionState) get() ConnectionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state
+}
+
+func (s *connectionState) addHandler(handler ConnectionStateHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers = append(s.handlers, handler)
+}
+
+// set changes the state on Connect and Disconnect
+func (s *connectionState) set(state ConnectionState, err error) {
+	s.change(state, err, false)
+}
+
+// update changes the state on the connection events of the library. The events are ignored once disconnected, as
+// the library reports the connection closed by Disconnect as lost.
+func (s *connectionState) update(state ConnectionState, err error) {
+	s.change(state, err, true)
+}
+
+func (s *connectionState) change(state ConnectionState, err error, ignoreDisconnected bool) {
+	s.mu.Lock()
+	if s.state == state || (ignoreDisconnected && s.state == ConnectionStateDisconnected) {
+		s.mu.Unlock()
+		return
+	}
+
+	s.state = state
+	if state == ConnectionStateConnecting {
+		s.lastError = nil
+	}
+
+	close(s.changed)
+	s.changed = make(chan struct{})
+
+	handlers := append([]ConnectionStateHandler(nil), s.handlers...)
+	s.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(state, err)
+	}
+}
+
+// waitFor waits until the state is reached or the context is done
+func (s *connectionState) waitFor(ctx context.Context, state ConnectionState) error {
+	for {
+		s.mu.Lock()
+		current, changed := s.state, s.changed
+		s.mu.Unlock()
+
+		if current == state {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// connectFailed records the error of a failed connection attempt
+func (s *connectionState) connectFailed(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastError = err
+}
+
+// lastConnectError returns the error of the last failed connection attempt since Connect
+func (s *connectionState) lastConnectError() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lastError
+}
+
+// inflightMessages counts the published messages waiting to be written or acknowledged by the broker, so Disconnect
+// can wait for the QoS 1 and 2 flows to complete
+type inflightMessages struct {
+	mu    sync.Mutex
+	count int
+	// done is closed when the count drops to zero
+	done chan struct{}
+}
+
+// add counts a published message until the returned function is called
+func (m *inflightMessages) add() func() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.count == 0 {
+		m.done = make(chan struct{})
+	}
+	m.count++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+
+			m.count--
+			if m.count == 0 {
+				close(m.done)
+			}
+		})
+	}
+}
+
+// wait waits until there are no messages in flight or the context is done
+func (m *inflightMessages) wait(ctx context.Context) error {
+	m.mu.Lock()
+	if m.count == 0 {
+		m.mu.Unlock()
+		return nil
+	}
+
+	done := m.done
+	m.mu.Unlock()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
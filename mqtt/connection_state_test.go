@@ -0,0 +1,136 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xBlaz3kx/DevX/observability"
+)
+
+type stateChange struct {
+	state ConnectionState
+	err   error
+}
+
+func TestConnectionState(t *testing.T) {
+	state := newConnectionState()
+	assert.Equal(t, ConnectionStateDisconnected, state.get())
+
+	var changes []stateChange
+	state.addHandler(func(state ConnectionState, err error) {
+		changes = append(changes, stateChange{state: state, err: err})
+	})
+
+	// The library events are ignored until Connect
+	state.update(ConnectionStateConnected, nil)
+	assert.Equal(t, ConnectionStateDisconnected, state.get())
+
+	errLost := errors.New("connection reset")
+	state.set(ConnectionStateConnecting, nil)
+	state.update(ConnectionStateConnected, nil)
+	state.update(ConnectionStateConnected, nil)
+	state.update(ConnectionStateConnectionLost, errLost)
+	state.update(ConnectionStateReconnecting, nil)
+	state.set(ConnectionStateDisconnected, nil)
+
+	// Closing the connection on Disconnect is not reported as lost
+	state.update(ConnectionStateConnectionLost, nil)
+
+	assert.Equal(t, []stateChange{
+		{state: ConnectionStateConnecting},
+		{state: ConnectionStateConnected},
+		{state: ConnectionStateConnectionLost, err: errLost},
+		{state: ConnectionStateReconnecting},
+		{state: ConnectionStateDisconnected},
+	}, changes)
+
+	// The error of the last attempt is kept until the next Connect
+	state.connectFailed(errLost)
+	assert.ErrorIs(t, state.lastConnectError(), errLost)
+	state.set(ConnectionStateConnecting, nil)
+	assert.NoError(t, state.lastConnectError())
+}
+
+func TestInflightMessages(t *testing.T) {
+	inflight := inflightMessages{}
+	assert.NoError(t, inflight.wait(context.Background()))
+
+	first := inflight.add()
+	second := inflight.add()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, inflight.wait(ctx), context.DeadlineExceeded)
+
+	first()
+	first()
+
+	waited := make(chan error)
+	go func() {
+		waited <- inflight.wait(context.Background())
+	}()
+
+	second()
+	select {
+	case err := <-waited:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("wait did not return after the messages were acknowledged")
+	}
+}
+
+// unreachableBroker returns the address of a closed local port
+func unreachableBroker(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	return "mqtt://" + address
+}
+
+func TestConnectFailure(t *testing.T) {
+	config := Configuration{
+		Address:          unreachableBroker(t),
+		ClientId:         "test",
+		ConnectTimeout:   time.Second,
+		ReconnectBackoff: ReconnectBackoff{InitialDelay: 10 * time.Millisecond, MaxDelay: 10 * time.Millisecond},
+	}
+
+	for name, newClient := range map[string]func(Configuration, observability.Observability) (Client, error){
+		"v3": NewV3Client,
+		"v5": NewV5Client,
+	} {
+		t.Run(name, func(t *testing.T) {
+			client, err := newClient(config, observability.NewNoopObservability())
+			require.NoError(t, err)
+
+			var states []ConnectionState
+			client.OnConnectionStateChange(func(state ConnectionState, _ error) {
+				states = append(states, state)
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+
+			// The error of the connection attempt is returned instead of the context error
+			err = client.Connect(ctx)
+			require.Error(t, err)
+			assert.NotErrorIs(t, err, context.DeadlineExceeded)
+
+			assert.Equal(t, ConnectionStateDisconnected, client.ConnectionState())
+			assert.Equal(t, []ConnectionState{ConnectionStateConnecting, ConnectionStateDisconnected}, states)
+			assert.False(t, client.Pass())
+
+			assert.NoError(t, client.Disconnect(context.Background()))
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package mqtt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xBlaz3kx/DevX/tls"
+)
+
+func TestConfigurationWithDefaults(t *testing.T) {
+	config := Configuration{}.withDefaults()
+	assert.Equal(t, 30*time.Second, config.KeepAlive)
+	assert.Equal(t, 10*time.Second, config.ConnectTimeout)
+	assert.Equal(t, ReconnectBackoff{InitialDelay: time.Second, MaxDelay: 5 * time.Second}, config.ReconnectBackoff)
+
+	config = Configuration{
+		KeepAlive:        time.Minute,
+		ConnectTimeout:   time.Second,
+		ReconnectBackoff: ReconnectBackoff{InitialDelay: 10 * time.Second},
+	}.withDefaults()
+	assert.Equal(t, time.Minute, config.KeepAlive)
+	assert.Equal(t, time.Second, config.ConnectTimeout)
+
+	// The maximum delay is never lower than the initial delay
+	assert.Equal(t, ReconnectBackoff{InitialDelay: 10 * time.Second, MaxDelay: 10 * time.Second}, config.ReconnectBackoff)
+}
+
+func TestReconnectBackoffDelay(t *testing.T) {
+	backoff := ReconnectBackoff{InitialDelay: time.Second, MaxDelay: 5 * time.Second}
+
+	assert.Equal(t, time.Duration(0), backoff.delay(0))
+	assert.Equal(t, time.Second, backoff.delay(1))
+	assert.Equal(t, 2*time.Second, backoff.delay(2))
+	assert.Equal(t, 4*time.Second, backoff.delay(3))
+	assert.Equal(t, 5*time.Second, backoff.delay(4))
+	assert.Equal(t, 5*time.Second, backoff.delay(100))
+}
+
+func TestConfigurationBrokerUrl(t *testing.T) {
+	tests := []struct {
+		address    string
+		tlsEnabled bool
+		expected   string
+	}{
+		{address: "mqtt://localhost:1883", expected: "mqtt://localhost:1883"},
+		{address: "tcp://localhost:1883", tlsEnabled: true, expected: "mqtts://localhost:1883"},
+		{address: "mqtt://localhost:8883", tlsEnabled: true, expected: "mqtts://localhost:8883"},
+		{address: "ws://localhost:8080/mqtt", tlsEnabled: true, expected: "wss://localhost:8080/mqtt"},
+		{address: "ssl://localhost:8883", tlsEnabled: true, expected: "ssl://localhost:8883"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			config := Configuration{Address: tt.address, TLS: tls.TLS{IsEnabled: tt.tlsEnabled}}
+
+			brokerUrl, err := config.brokerUrl()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, brokerUrl.String())
+		})
+	}
+}
+
+func TestConfigurationTlsConfig(t *testing.T) {
+	tlsConfig, err := Configuration{}.tlsConfig()
+	assert.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+
+	// Server authentication only
+	tlsConfig, err = Configuration{TLS: tls.TLS{IsEnabled: true}}.tlsConfig()
+	require.NoError(t, err)
+	assert.NotNil(t, tlsConfig.RootCAs)
+	assert.Empty(t, tlsConfig.Certificates)
+
+	_, err = Configuration{TLS: tls.TLS{IsEnabled: true, CertificatePath: "missing.crt", PrivateKeyPath: "missing.key"}}.tlsConfig()
+	assert.Error(t, err)
+}
@@ -0,0 +1,417 @@
+package mqtt
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// FakeMessage is a message published with the FakeClient
+type FakeMessage struct {
+	Topic          Topic
+	Payload        []byte
+	QoS            byte
+	Retain         bool
+	ContentType    string
+	UserProperties []UserProperty
+	// ResponseTopic and CorrelationData are set on the requests of PublishRPC and their responses
+	ResponseTopic   Topic
+	CorrelationData []byte
+	PublishedAt     time.Time
+}
+
+// FakeClient is an in-memory Client for unit tests. The published messages are encoded with the codec, recorded and
+// delivered to the matching subscriptions of the client, like through a broker: with wildcard and shared subscription
+// matching, retained messages and request/response. The messages are delivered synchronously before Publish returns.
+type FakeClient struct {
+	id    string
+	codec Codec
+	state *connectionState
+
+	// subscriptions deliver the messages to the handlers
+	subscriptions *subscriptionRegistry[func(message FakeMessage)]
+
+	mu        sync.Mutex
+	published []FakeMessage
+	retained  map[Topic]FakeMessage
+	packetId  uint16
+
+	// responseTopic receives the responses to the requests of PublishRPC
+	responseTopic Topic
+	pending       *pendingRequests[FakeMessage]
+}
+
+// NewFakeClient creates a disconnected fake client with the client id and codec of the configuration
+func NewFakeClient(config Configuration) (*FakeClient, error) {
+	codec, err := CodecByName(config.Codec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FakeClient{
+		id:            config.ClientId,
+		codec:         codec,
+		state:         newConnectionState(),
+		subscriptions: newSubscriptionRegistry[func(message FakeMessage)](),
+		retained:      make(map[Topic]FakeMessage),
+		responseTopic: newResponseTopic(config.ClientId),
+		pending:       newPendingRequests[FakeMessage](),
+	}, nil
+}
+
+// Connect connects the client and activates the subscriptions
+func (c *FakeClient) Connect(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return errors.Wrap(err, "failed to connect to the broker")
+	}
+
+	c.state.set(ConnectionStateConnected, nil)
+	for _, sub := range c.subscriptions.all() {
+		c.subscriptions.setResult(sub.info.Topic, nil)
+		c.deliverRetained(sub.info.Topic, sub.handler)
+	}
+
+	return nil
+}
+
+// Disconnect disconnects the client. The subscriptions are kept for the next Connect.
+func (c *FakeClient) Disconnect(_ context.Context) error {
+	c.state.set(ConnectionStateDisconnected, nil)
+	c.subscriptions.setPending()
+	return nil
+}
+
+// LoseConnection simulates losing the connection to the broker, until the next Connect
+func (c *FakeClient) LoseConnection(err error) {
+	c.state.update(ConnectionStateConnectionLost, err)
+	c.subscriptions.setPending()
+}
+
+// Publish encodes the message, records it and delivers it to the matching subscriptions
+func (c *FakeClient) Publish(ctx context.Context, topic Topic, message interface{}, opts ...PublishOpt) error {
+	if c.state.get() != ConnectionStateConnected {
+		return ErrNotConnected
+	}
+
+	if topic == "" || strings.ContainsAny(topic.String(), "+#") {
+		return errors.Errorf("invalid topic %q", topic)
+	}
+
+	fakeMessage, err := newFakeMessage(c.codec, topic, message, newPublishOptions(0, opts...))
+	if err != nil {
+		return err
+	}
+
+	c.publish(fakeMessage)
+	return ctx.Err()
+}
+
+// newFakeMessage encodes the message with the options
+func newFakeMessage(codec Codec, topic Topic, message interface{}, options *PublishOptions) (FakeMessage, error) {
+	payload, err := options.encode(codec, message)
+	if err != nil {
+		return FakeMessage{}, err
+	}
+
+	return FakeMessage{
+		Topic:          topic,
+		Payload:        payload,
+		QoS:            options.qos,
+		Retain:         options.retain,
+		ContentType:    options.contentType,
+		UserProperties: options.userProperties,
+	}, nil
+}
+
+// publish records the message and delivers it to the matching subscriptions
+func (c *FakeClient) publish(message FakeMessage) {
+	message.PublishedAt = time.Now()
+	c.record(message)
+	c.route(message)
+}
+
+// record keeps the published message and the retained messages
+func (c *FakeClient) record(message FakeMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.published = append(c.published, message)
+
+	if message.Retain {
+		// An empty retained message clears the retained message of the topic
+		if len(message.Payload) == 0 {
+			delete(c.retained, message.Topic)
+		} else {
+			c.retained[message.Topic] = message
+		}
+	}
+}
+
+// route delivers the message to the handlers of the matching subscriptions, outside the lock, so the handlers can
+// publish messages themselves
+func (c *FakeClient) route(message FakeMessage) {
+	for _, sub := range c.subscriptions.all() {
+		if sub.info.State != SubscriptionActive {
+			continue
+		}
+
+		if _, err := matchTopic(stripSharePrefix(sub.info.Topic).String(), message.Topic.String()); err == nil {
+			sub.handler(message)
+		}
+	}
+}
+
+// deliverRetained delivers the retained messages matching the topic filter to the handler
+func (c *FakeClient) deliverRetained(topic Topic, handler func(message FakeMessage)) {
+	c.mu.Lock()
+	var retained []FakeMessage
+	for _, message := range c.retained {
+		if _, err := matchTopic(stripSharePrefix(topic).String(), message.Topic.String()); err == nil {
+			retained = append(retained, message)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, message := range retained {
+		handler(message)
+	}
+}
+
+// Published returns the messages published with the client
+func (c *FakeClient) Published() []FakeMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]FakeMessage(nil), c.published...)
+}
+
+// PublishedTo returns the messages published to the topics matching the topic filter
+func (c *FakeClient) PublishedTo(filter Topic) []FakeMessage {
+	var messages []FakeMessage
+	for _, message := range c.Published() {
+		if _, err := matchTopic(filter.String(), message.Topic.String()); err == nil {
+			messages = append(messages, message)
+		}
+	}
+
+	return messages
+}
+
+// Reset clears the published and retained messages
+func (c *FakeClient) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.published = nil
+	c.retained = make(map[Topic]FakeMessage)
+}
+
+// nextPacketId returns the packet id of a delivered message. QoS 0 messages have no packet id.
+func (c *FakeClient) nextPacketId(qos byte) uint16 {
+	if qos == 0 {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.packetId = max(c.packetId+1, 1)
+	return c.packetId
+}
+
+// messageCodec returns the codec of the message content type, falling back to the codec of the subscription or the client
+func (c *FakeClient) messageCodec(message FakeMessage, options *SubscribeOptions) Codec {
+	codec, ok := codecByContentType(message.ContentType, options.codec, c.codec)
+	if ok {
+		return codec
+	}
+
+	return options.codecOrDefault(c.codec)
+}
+
+func (c *FakeClient) decode(message FakeMessage, options *SubscribeOptions) (interface{}, error) {
+	return options.decode(c.messageCodec(message, options), message.Payload)
+}
+
+// subscribe registers the subscription, which is active while the client is connected
+func (c *FakeClient) subscribe(topic Topic, handler func(message FakeMessage)) error {
+	if !isValidFilter(stripSharePrefix(topic).String()) {
+		return errors.Errorf("invalid topic filter %q", topic)
+	}
+
+	c.subscriptions.add(topic, 1, handler)
+
+	if c.state.get() == ConnectionStateConnected {
+		c.subscriptions.setResult(topic, nil)
+		c.deliverRetained(topic, handler)
+	}
+
+	return nil
+}
+
+// Subscribe to a topic. The handler receives the levels of the actual topic as ids.
+func (c *FakeClient) Subscribe(_ context.Context, topic Topic, handler Handler, opts ...SubscribeOpt) error {
+	options := newSubscribeOptions(opts...)
+
+	return c.subscribe(topic, func(message FakeMessage) {
+		data, err := c.decode(message, options)
+		if err != nil {
+			return
+		}
+
+		handler(c, strings.Split(message.Topic.String(), "/"), c.nextPacketId(message.QoS), data, nil)
+	})
+}
+
+// SubscribeWithId to a topic. The handler receives the values of the topic wildcards as ids.
+func (c *FakeClient) SubscribeWithId(_ context.Context, topic Topic, handler Handler, opts ...SubscribeOpt) {
+	_ = c.subscribe(topic, c.idsMessageHandler(topic, handler, newSubscribeOptions(opts...)))
+}
+
+// SubscribeShared subscribes to a topic as a member of the shared subscription group
+func (c *FakeClient) SubscribeShared(_ context.Context, group string, topic Topic, handler Handler, opts ...SubscribeOpt) error {
+	sharedTopic, err := SharedTopic(group, topic)
+	if err != nil {
+		return err
+	}
+
+	return c.subscribe(sharedTopic, c.idsMessageHandler(topic, handler, newSubscribeOptions(opts...)))
+}
+
+// idsMessageHandler decodes the payload and passes the values of the topic wildcards to the handler
+func (c *FakeClient) idsMessageHandler(topic Topic, handler Handler, options *SubscribeOptions) func(message FakeMessage) {
+	return func(message FakeMessage) {
+		packetId := c.nextPacketId(message.QoS)
+
+		data, err := c.decode(message, options)
+		if err != nil {
+			handler(c, nil, packetId, nil, err)
+			return
+		}
+
+		ids, err := matchTopic(topic.String(), message.Topic.String())
+		if err != nil {
+			handler(c, nil, packetId, nil, err)
+			return
+		}
+
+		handler(c, ids, packetId, data, nil)
+	}
+}
+
+// PublishRPC publishes the request with the response topic and correlation data set and waits for the response of a
+// SubscribeRPC handler or the context to be done
+func (c *FakeClient) PublishRPC(ctx context.Context, topic Topic, message interface{}, opts ...PublishOpt) ([]byte, error) {
+	if c.state.get() != ConnectionStateConnected {
+		return nil, ErrNotConnected
+	}
+
+	request, err := newFakeMessage(c.codec, topic, message, newPublishOptions(0, opts...))
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.subscribe(c.responseTopic, func(message FakeMessage) {
+		c.pending.resolve(string(message.CorrelationData), message)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	correlationId := uuid.NewString()
+	response := c.pending.add(correlationId)
+	defer c.pending.remove(correlationId)
+
+	request.ResponseTopic = c.responseTopic
+	request.CorrelationData = []byte(correlationId)
+	c.publish(request)
+
+	select {
+	case reply := <-response:
+		for _, property := range reply.UserProperties {
+			if property.Key == userPropertyError {
+				return reply.Payload, &ResponseError{Message: property.Value}
+			}
+		}
+
+		return reply.Payload, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// SubscribeRPC subscribes to requests on the topic and publishes the responses of the handler to the response topic
+// of the requests
+func (c *FakeClient) SubscribeRPC(_ context.Context, topic Topic, handler RequestHandler, opts ...SubscribeOpt) error {
+	options := newSubscribeOptions(opts...)
+
+	return c.subscribe(topic, func(message FakeMessage) {
+		var (
+			ids      []string
+			request  interface{}
+			response interface{}
+			err      error
+		)
+
+		if strings.ContainsAny(topic.String(), "+#") {
+			ids, err = matchTopic(stripSharePrefix(topic).String(), message.Topic.String())
+		}
+
+		codec := c.messageCodec(message, options)
+		if err == nil {
+			request, err = options.decode(codec, message.Payload)
+		}
+
+		if err == nil {
+			response, err = handler(context.Background(), ids, request)
+		}
+
+		if message.ResponseTopic == "" {
+			return
+		}
+
+		reply := FakeMessage{Topic: message.ResponseTopic, QoS: message.QoS, CorrelationData: message.CorrelationData}
+		if err == nil && response != nil {
+			reply, err = newFakeMessage(codec, message.ResponseTopic, response, newPublishOptions(message.QoS))
+			reply.Topic, reply.CorrelationData = message.ResponseTopic, message.CorrelationData
+		}
+
+		if err != nil {
+			reply.UserProperties = append(reply.UserProperties, UserProperty{Key: userPropertyError, Value: err.Error()})
+		}
+
+		c.publish(reply)
+	})
+}
+
+// Subscriptions returns the state of the subscriptions
+func (c *FakeClient) Subscriptions() []SubscriptionInfo {
+	return c.subscriptions.list()
+}
+
+// ConnectionState returns the current state of the connection
+func (c *FakeClient) ConnectionState() ConnectionState {
+	return c.state.get()
+}
+
+// OnConnectionStateChange registers a handler called whenever the connection state changes
+func (c *FakeClient) OnConnectionStateChange(handler ConnectionStateHandler) {
+	c.state.addHandler(handler)
+}
+
+func (c *FakeClient) GetId() string {
+	return c.id
+}
+
+// Pass reports whether the client is connected
+func (c *FakeClient) Pass() bool {
+	return c.state.get() == ConnectionStateConnected
+}
+
+func (c *FakeClient) Name() string {
+	return "mqtt-fake"
+}
@@ -0,0 +1,159 @@
+package mqtt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFakeClient(t *testing.T) *FakeClient {
+	t.Helper()
+
+	client, err := NewFakeClient(Configuration{ClientId: "test"})
+	require.NoError(t, err)
+	require.NoError(t, client.Connect(context.Background()))
+
+	return client
+}
+
+func TestFakeClientRouting(t *testing.T) {
+	client := newTestFakeClient(t)
+
+	var received []receivedMessage
+	handler := func(_ Client, ids []string, payloadId uint16, payload interface{}, err error) {
+		received = append(received, receivedMessage{ids: ids, payloadId: payloadId, payload: payload, err: err})
+	}
+
+	client.SubscribeWithId(context.Background(), "devices/+/sensors/#", handler)
+	require.NoError(t, client.SubscribeShared(context.Background(), "ingestion", "$SYS/+", handler))
+	require.NoError(t, client.Subscribe(context.Background(), "#", handler))
+
+	require.NoError(t, client.Publish(context.Background(), "devices/device1/sensors/42/data", map[string]int{"value": 3}, WithQoS(1)))
+
+	// The subscriptions receive the message in the order of their topics
+	require.Len(t, received, 2)
+	assert.Equal(t, []string{"devices", "device1", "sensors", "42", "data"}, received[0].ids)
+	assert.Equal(t, []string{"device1", "42/data"}, received[1].ids)
+	assert.Equal(t, map[string]interface{}{"value": float64(3)}, received[1].payload)
+	assert.NotZero(t, received[1].payloadId)
+
+	// Leading wildcards do not match the system topics
+	received = nil
+	require.NoError(t, client.Publish(context.Background(), "$SYS/uptime", 42))
+	require.Len(t, received, 1)
+	assert.Equal(t, []string{"uptime"}, received[0].ids)
+	assert.Equal(t, uint16(0), received[0].payloadId)
+
+	// Payloads are decoded with the codec of the content type
+	received = nil
+	require.NoError(t, client.Publish(context.Background(), "devices/device1/sensors/42", []byte("raw"), WithCodec(RawCodec)))
+	require.Len(t, received, 2)
+	assert.Equal(t, []byte("raw"), received[0].payload)
+	assert.Equal(t, []byte("raw"), received[1].payload)
+
+	assert.Len(t, client.Published(), 3)
+	assert.Len(t, client.PublishedTo("devices/+/sensors/#"), 2)
+	assert.ErrorContains(t, client.Publish(context.Background(), "devices/+/status", nil), "invalid topic")
+}
+
+func TestFakeClientDecodeError(t *testing.T) {
+	client := newTestFakeClient(t)
+
+	var received []receivedMessage
+	client.SubscribeWithId(context.Background(), "devices/+/status", func(_ Client, ids []string, payloadId uint16, payload interface{}, err error) {
+		received = append(received, receivedMessage{ids: ids, payload: payload, err: err})
+	})
+
+	require.NoError(t, client.Publish(context.Background(), "devices/device1/status", []byte("not json"), WithCodec(RawCodec), WithContentType("application/json")))
+
+	require.Len(t, received, 1)
+	assert.Error(t, received[0].err)
+	assert.Nil(t, received[0].payload)
+}
+
+func TestFakeClientRetained(t *testing.T) {
+	client := newTestFakeClient(t)
+
+	require.NoError(t, client.Publish(context.Background(), "devices/device1/config", "retained", WithRetain(true)))
+	require.NoError(t, client.Publish(context.Background(), "devices/device2/config", "retained", WithRetain(true)))
+	require.NoError(t, client.Publish(context.Background(), "devices/device2/config", []byte{}, WithRetain(true), WithCodec(RawCodec)))
+
+	var ids [][]string
+	client.SubscribeWithId(context.Background(), "devices/+/config", func(_ Client, topicIds []string, _ uint16, _ interface{}, _ error) {
+		ids = append(ids, topicIds)
+	})
+
+	// The empty retained message cleared the retained message of device2
+	assert.Equal(t, [][]string{{"device1"}}, ids)
+}
+
+func TestFakeClientConnection(t *testing.T) {
+	client, err := NewFakeClient(Configuration{ClientId: "test"})
+	require.NoError(t, err)
+
+	var states []ConnectionState
+	client.OnConnectionStateChange(func(state ConnectionState, _ error) {
+		states = append(states, state)
+	})
+
+	var received int
+	client.SubscribeWithId(context.Background(), "devices/+/status", func(Client, []string, uint16, interface{}, error) {
+		received++
+	})
+
+	// The subscriptions are pending until connected
+	assert.ErrorIs(t, client.Publish(context.Background(), "devices/device1/status", "online"), ErrNotConnected)
+	assert.Equal(t, SubscriptionPending, client.Subscriptions()[0].State)
+	assert.False(t, client.Pass())
+
+	require.NoError(t, client.Connect(context.Background()))
+	assert.Equal(t, SubscriptionActive, client.Subscriptions()[0].State)
+	assert.True(t, client.Pass())
+
+	client.LoseConnection(errors.New("connection reset"))
+	assert.Equal(t, SubscriptionPending, client.Subscriptions()[0].State)
+
+	require.NoError(t, client.Connect(context.Background()))
+	require.NoError(t, client.Publish(context.Background(), "devices/device1/status", "online"))
+	require.NoError(t, client.Disconnect(context.Background()))
+
+	assert.Equal(t, 1, received)
+	assert.Equal(t, []ConnectionState{
+		ConnectionStateConnected,
+		ConnectionStateConnectionLost,
+		ConnectionStateConnected,
+		ConnectionStateDisconnected,
+	}, states)
+}
+
+func TestFakeClientRPC(t *testing.T) {
+	client := newTestFakeClient(t)
+
+	err := client.SubscribeRPC(context.Background(), "devices/+/reset", func(_ context.Context, ids []string, request interface{}) (interface{}, error) {
+		if request == "fail" {
+			return nil, errors.New("device is busy")
+		}
+
+		return map[string]string{"deviceId": ids[0], "mode": request.(string)}, nil
+	})
+	require.NoError(t, err)
+
+	response, err := client.PublishRPC(context.Background(), "devices/device1/reset", "hard")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"deviceId":"device1","mode":"hard"}`, string(response))
+
+	_, err = client.PublishRPC(context.Background(), "devices/device1/reset", "fail")
+	assert.ErrorIs(t, err, ErrResponse)
+	assert.ErrorContains(t, err, "device is busy")
+
+	// Requests without a responder time out
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = client.PublishRPC(ctx, "other/device1/reset", "hard")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
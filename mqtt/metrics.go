@@ -0,0 +1,137 @@
+package mqtt
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	mqttOfflineQueueMessages     = "mqtt_offline_queue_messages"
+	mqttOfflineQueueDroppedTotal = "mqtt_offline_queue_dropped_total"
+	mqttMessagesPublishedTotal   = "mqtt_messages_published_total"
+	mqttPublishDurationSeconds   = "mqtt_publish_duration_seconds"
+	mqttMessagesReceivedTotal    = "mqtt_messages_received_total"
+	mqttDecodeFailuresTotal      = "mqtt_decode_failures_total"
+	mqttHandlerDurationSeconds   = "mqtt_handler_duration_seconds"
+
+	attrClientId    = "client_id"
+	attrReason      = "reason"
+	attrTopicFilter = "topic_filter"
+	attrStatus      = "status"
+)
+
+type mqttMetrics struct {
+	offlineQueueMessages metric.Int64Gauge
+	offlineQueueDropped  metric.Int64Counter
+	messagesPublished    metric.Int64Counter
+	publishDuration      metric.Float64Histogram
+	messagesReceived     metric.Int64Counter
+	decodeFailures       metric.Int64Counter
+	handlerDuration      metric.Float64Histogram
+}
+
+// Initializes the mqtt meters
+func newMqttMetrics() (metrics mqttMetrics, err error) {
+	meter := otel.Meter("mqtt")
+
+	if metrics.offlineQueueMessages, err = meter.Int64Gauge(
+		mqttOfflineQueueMessages,
+		metric.WithDescription("Number of messages waiting in the offline publish queue"),
+	); err != nil {
+		return mqttMetrics{}, errors.Wrap(err, "failed to create mqtt_offline_queue_messages metric")
+	}
+
+	if metrics.offlineQueueDropped, err = meter.Int64Counter(
+		mqttOfflineQueueDroppedTotal,
+		metric.WithDescription("Total number of messages dropped from the offline publish queue"),
+	); err != nil {
+		return mqttMetrics{}, errors.Wrap(err, "failed to create mqtt_offline_queue_dropped_total metric")
+	}
+
+	if metrics.messagesPublished, err = meter.Int64Counter(
+		mqttMessagesPublishedTotal,
+		metric.WithDescription("Total number of published messages"),
+	); err != nil {
+		return mqttMetrics{}, errors.Wrap(err, "failed to create mqtt_messages_published_total metric")
+	}
+
+	if metrics.publishDuration, err = meter.Float64Histogram(
+		mqttPublishDurationSeconds,
+		metric.WithDescription("The publish latencies in seconds, including the broker acknowledgement"),
+		metric.WithUnit("s"),
+	); err != nil {
+		return mqttMetrics{}, errors.Wrap(err, "failed to create mqtt_publish_duration_seconds metric")
+	}
+
+	if metrics.messagesReceived, err = meter.Int64Counter(
+		mqttMessagesReceivedTotal,
+		metric.WithDescription("Total number of received messages"),
+	); err != nil {
+		return mqttMetrics{}, errors.Wrap(err, "failed to create mqtt_messages_received_total metric")
+	}
+
+	if metrics.decodeFailures, err = meter.Int64Counter(
+		mqttDecodeFailuresTotal,
+		metric.WithDescription("Total number of received payloads that could not be decoded"),
+	); err != nil {
+		return mqttMetrics{}, errors.Wrap(err, "failed to create mqtt_decode_failures_total metric")
+	}
+
+	if metrics.handlerDuration, err = meter.Float64Histogram(
+		mqttHandlerDurationSeconds,
+		metric.WithDescription("The message handler latencies in seconds"),
+		metric.WithUnit("s"),
+	); err != nil {
+		return mqttMetrics{}, errors.Wrap(err, "failed to create mqtt_handler_duration_seconds metric")
+	}
+
+	return
+}
+
+func (m *mqttMetrics) RecordOfflineQueueMessages(clientId string, messages int) {
+	m.offlineQueueMessages.Record(context.Background(), int64(messages),
+		metric.WithAttributes(attribute.String(attrClientId, clientId)),
+	)
+}
+
+func (m *mqttMetrics) IncrementOfflineQueueDropped(clientId string, reason string, dropped int) {
+	m.offlineQueueDropped.Add(context.Background(), int64(dropped),
+		metric.WithAttributes(
+			attribute.String(attrClientId, clientId),
+			attribute.String(attrReason, reason),
+		),
+	)
+}
+
+func (m *mqttMetrics) RecordMessagePublished(topicFilter string, duration time.Duration, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+
+	attributes := metric.WithAttributes(
+		attribute.String(attrTopicFilter, topicFilter),
+		attribute.String(attrStatus, status),
+	)
+
+	m.messagesPublished.Add(context.Background(), 1, attributes)
+	m.publishDuration.Record(context.Background(), duration.Seconds(), attributes)
+}
+
+func (m *mqttMetrics) RecordMessageReceived(topicFilter Topic, handlerDuration time.Duration) {
+	attributes := metric.WithAttributes(attribute.String(attrTopicFilter, topicFilter.String()))
+
+	m.messagesReceived.Add(context.Background(), 1, attributes)
+	m.handlerDuration.Record(context.Background(), handlerDuration.Seconds(), attributes)
+}
+
+func (m *mqttMetrics) IncrementDecodeFailures(topicFilter Topic) {
+	m.decodeFailures.Add(context.Background(), 1,
+		metric.WithAttributes(attribute.String(attrTopicFilter, topicFilter.String())),
+	)
+}
@@ -2,119 +2,401 @@ package mqtt
 
 import (
 	"context"
-	"encoding/json"
 	"strings"
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/pkg/errors"
 	"github.com/xBlaz3kx/DevX/observability"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+// disconnectQuiesce is the time in milliseconds the library waits for the disconnect packet to be sent
+const disconnectQuiesce = 250
+
 // mqttV3 concrete implementation of the Client, which is essentially a wrapper over the mqtt lib.
 type mqttV3 struct {
 	obs        observability.Observability
 	mqttClient mqtt.Client
 	id         string
+	codec      Codec
+
+	// producer and consumer create the spans of the published and received messages
+	producer observability.Observability
+	consumer observability.Observability
+
+	// traceEnvelope propagates the trace context in an envelope around the payload
+	traceEnvelope bool
+
+	// birthMessage and deathMessage are published after connecting and before disconnecting, nil if disabled
+	birthMessage   *PresenceMessage
+	deathMessage   *PresenceMessage
+	connectTimeout time.Duration
+
+	// subscriptions are restored after every (re)connection, as the library does not restore them
+	subscriptions *subscriptionRegistry[mqtt.MessageHandler]
+
+	// queue keeps the messages published while disconnected, nil if disabled
+	queue   *offlineQueue
+	metrics mqttMetrics
+
+	state    *connectionState
+	inflight inflightMessages
 }
 
 // NewV3Client creates a wrapped mqtt Client with specific settings.
 func NewV3Client(clientSettings Configuration, obs observability.Observability) (Client, error) {
+	clientSettings = clientSettings.withDefaults()
+	if err := clientSettings.validateSession(); err != nil {
+		return nil, err
+	}
+
+	if err := clientSettings.validatePresence(); err != nil {
+		return nil, err
+	}
+
+	brokerUrl, err := clientSettings.brokerUrl()
+	if err != nil {
+		return nil, err
+	}
+
+	codec, err := CodecByName(clientSettings.Codec)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := newMqttMetrics()
+	if err != nil {
+		return nil, err
+	}
+
+	queue, err := newClientQueue(clientSettings, metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &mqttV3{
+		id:             clientSettings.ClientId,
+		codec:          codec,
+		obs:            obs,
+		producer:       obs.WithSpanKind(trace.SpanKindProducer),
+		consumer:       obs.WithSpanKind(trace.SpanKindConsumer),
+		traceEnvelope:  clientSettings.TraceEnvelope,
+		birthMessage:   clientSettings.BirthMessage,
+		deathMessage:   clientSettings.DeathMessage,
+		connectTimeout: clientSettings.ConnectTimeout,
+		subscriptions:  newSubscriptionRegistry[mqtt.MessageHandler](),
+		queue:          queue,
+		metrics:        metrics,
+		state:          newConnectionState(),
+	}
+
 	// Basic client settings
 	opts := mqtt.NewClientOptions()
-	opts.AddBroker(clientSettings.Address)
+	opts.AddBroker(brokerUrl.String())
 	opts.SetClientID(clientSettings.ClientId)
 	opts.SetUsername(clientSettings.Username)
 	opts.SetPassword(clientSettings.Password)
 
 	// Connection settings
-	opts.SetKeepAlive(30 * time.Second)
+	opts.SetKeepAlive(clientSettings.KeepAlive)
+	opts.SetConnectTimeout(clientSettings.ConnectTimeout)
 	opts.SetAutoReconnect(true)
-	opts.SetCleanSession(true)
-	opts.SetMaxReconnectInterval(time.Second * 5)
+	opts.SetCleanSession(!clientSettings.PersistentSession)
+	opts.SetMaxReconnectInterval(clientSettings.ReconnectBackoff.MaxDelay)
 
 	// Append certs if enabled
-	if clientSettings.TLS.IsEnabled {
-		tlsSettings, err := clientSettings.TLS.ToTlsConfig()
-		if err != nil {
-			return nil, err
-		}
+	tlsSettings, err := clientSettings.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
 
+	if tlsSettings != nil {
 		opts.SetTLSConfig(tlsSettings)
 	}
 
-	opts.SetOnConnectHandler(func(client mqtt.Client) {
+	if will := clientSettings.LastWill; will != nil {
+		opts.SetBinaryWill(will.Topic.String(), []byte(will.Payload), will.QoS, will.Retain)
+	}
+
+	opts.SetOnConnectHandler(func(_ mqtt.Client) {
 		obs.Log().Info("Connected to broker")
+
+		// Disconnect waits for the birth message, so it is not published after the death message
+		birthPublished := client.inflight.add()
+		client.state.update(ConnectionStateConnected, nil)
+
+		// The handler must not block the connection
+		go func() {
+			client.publishBirth()
+			birthPublished()
+			client.resubscribe()
+			client.drainQueue()
+		}()
 	})
 
 	opts.SetDefaultPublishHandler(func(client mqtt.Client, message mqtt.Message) {
 		obs.Log().Sugar().Infof("Received message %s from topic %s", message.Payload(), message.Topic())
 	})
 
-	opts.SetConnectionLostHandler(func(client mqtt.Client, err error) {
+	opts.SetConnectionLostHandler(func(_ mqtt.Client, err error) {
 		obs.Log().Info("Disconnected from broker", zap.Error(err))
+		client.subscriptions.setPending()
+		client.state.update(ConnectionStateConnectionLost, err)
+	})
+
+	opts.SetReconnectingHandler(func(_ mqtt.Client, _ *mqtt.ClientOptions) {
+		client.state.update(ConnectionStateReconnecting, nil)
 	})
 
 	// Connect to the MQTT broker
-	client := mqtt.NewClient(opts)
-	return &mqttV3{
-		mqttClient: client,
-		id:         clientSettings.ClientId,
-		obs:        obs,
-	}, nil
+	client.mqttClient = mqtt.NewClient(opts)
+	return client, nil
 }
 
-func (c *mqttV3) Connect(_ context.Context) error {
+// Connect connects to the broker and waits for the connection or the context to be done.
+// If the context is done first, the connection attempt is abandoned.
+func (c *mqttV3) Connect(ctx context.Context) error {
 	c.obs.Log().Debug("Connecting to the MQTT broker")
-	c.mqttClient.Connect().Wait()
-	return nil
+	c.state.set(ConnectionStateConnecting, nil)
+
+	token := c.mqttClient.Connect()
+	err := waitToken(ctx, token)
+	if err == nil {
+		// The connect handler is called asynchronously after the connection is up
+		err = c.state.waitFor(ctx, ConnectionStateConnected)
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	if ctx.Err() != nil {
+		// Close the connection if the attempt succeeds after all
+		go func() {
+			if token.Wait() && token.Error() == nil {
+				c.mqttClient.Disconnect(0)
+			}
+		}()
+	}
+
+	c.state.set(ConnectionStateDisconnected, err)
+	return errors.Wrap(err, "failed to connect to the broker")
 }
 
-func (c *mqttV3) Disconnect(_ context.Context) error {
+// Disconnect waits for the messages in flight until the context is done, publishes the death message and
+// disconnects. Returns the context error if the messages in flight were not acknowledged in time.
+func (c *mqttV3) Disconnect(ctx context.Context) error {
 	c.obs.Log().Debug("Disconnecting the MQTT client")
-	c.mqttClient.Disconnect(100)
-	return nil
+
+	inflightErr := c.inflight.wait(ctx)
+	if inflightErr != nil {
+		c.obs.Log().Warn("Disconnecting with messages in flight", zap.Error(inflightErr))
+		inflightErr = errors.Wrap(inflightErr, "messages in flight were not acknowledged")
+	}
+
+	if c.deathMessage != nil && c.mqttClient.IsConnectionOpen() {
+		c.publishPresence(ctx, c.deathMessage)
+	}
+
+	c.state.set(ConnectionStateDisconnected, nil)
+	c.mqttClient.Disconnect(disconnectQuiesce)
+
+	if c.queue != nil {
+		if err := c.queue.close(); err != nil {
+			return err
+		}
+	}
+
+	return inflightErr
+}
+
+// publishBirth publishes the birth message after a (re)connection
+func (c *mqttV3) publishBirth() {
+	if c.birthMessage == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.connectTimeout)
+	defer cancel()
+
+	c.publishPresence(ctx, c.birthMessage)
+}
+
+// publishPresence publishes the presence message. Failures are logged, as they must not prevent (dis)connecting.
+func (c *mqttV3) publishPresence(ctx context.Context, message *PresenceMessage) {
+	err := waitToken(ctx, c.mqttClient.Publish(message.Topic.String(), message.QoS, message.Retain, []byte(message.Payload)))
+	if err != nil {
+		c.obs.Log().Warn("Unable to publish the presence message", zap.Error(err), zap.String("topic", message.Topic.String()))
+	}
 }
 
 func (c *mqttV3) GetId() string {
 	return c.id
 }
 
-// Publish a new message to a topic
-func (c *mqttV3) Publish(_ context.Context, topic Topic, message interface{}) error {
+// Publish a new message to a topic. The message is published with QoS 1 by default.
+// With QoS 1 or 2, it waits for the broker acknowledgement or the context to be done.
+// With the trace envelope enabled, the trace context is propagated in an envelope around the payload.
+func (c *mqttV3) Publish(ctx context.Context, topic Topic, message interface{}, opts ...PublishOpt) error {
+	options := newPublishOptions(1, opts...)
+	filter := options.metricsFilter(topic)
+
+	ctx, endSpan := c.producer.Span(ctx, "publish "+filter, publishSpanFields(c.id, topic, filter)...)
+	defer endSpan()
+
+	start := time.Now()
+	err := c.publish(ctx, topic, message, options)
+	recordSpanError(ctx, err)
+	c.metrics.RecordMessagePublished(filter, time.Since(start), err)
+
+	return err
+}
+
+func (c *mqttV3) publish(ctx context.Context, topic Topic, message interface{}, options *PublishOptions) error {
 	logInfo := c.obs.Log().With(
 		zap.String("topic", topic.String()),
 		zap.Any("message", message),
+		zap.Uint8("qos", options.qos),
 	)
 	logInfo.Debug("Publishing a message to topic")
 
-	jsonMessage, err := json.Marshal(message)
+	payload, err := options.encode(c.codec, message)
 	if err != nil {
 		return err
 	}
 
-	token := c.mqttClient.Publish(topic.String(), 1, false, jsonMessage)
+	if c.traceEnvelope {
+		payload, err = wrapTraceEnvelope(ctx, payload, options.codec)
+		if err != nil {
+			return errors.Wrap(err, "failed to wrap the payload in the trace envelope")
+		}
+	}
+
+	// Keep the order of the queued messages until the queue is drained
+	if c.queue != nil && (!c.mqttClient.IsConnectionOpen() || c.queue.len() > 0) {
+		return c.enqueue(newQueuedMessage(topic, payload, options))
+	}
+
+	done := c.inflight.add()
+	token := c.mqttClient.Publish(topic.String(), options.qos, options.retain, payload)
+	if options.qos > 0 {
+		// The flow is in flight until acknowledged, even if the context is done first
+		go func() {
+			token.Wait()
+			done()
+		}()
+
+		err = waitToken(ctx, token)
+		if c.queue != nil && errors.Is(err, mqtt.ErrNotConnected) {
+			return c.enqueue(newQueuedMessage(topic, payload, options))
+		}
+
+		return err
+	}
+
 	go func(token mqtt.Token) {
+		token.Wait()
+		done()
 		if token.Error() != nil {
-			c.obs.Log().Warn("Token error", zap.Error(token.Error()))
+			logInfo.Warn("Token error", zap.Error(token.Error()))
 		}
 	}(token)
 	return nil
 }
 
+// enqueue queues the message until the client is connected. If the client is connected, the queue is drained.
+func (c *mqttV3) enqueue(message queuedMessage) error {
+	c.obs.Log().Debug("Queueing the message until the client is connected", zap.String("topic", message.Topic.String()))
+
+	err := c.queue.enqueue(message)
+	if err != nil {
+		return err
+	}
+
+	if c.mqttClient.IsConnectionOpen() {
+		go c.drainQueue()
+	}
+
+	return nil
+}
+
+// drainQueue publishes the queued messages in order
+func (c *mqttV3) drainQueue() {
+	if c.queue == nil {
+		return
+	}
+
+	err := c.queue.drain(func(message queuedMessage) error {
+		ctx, cancel := context.WithTimeout(context.Background(), drainPublishTimeout)
+		defer cancel()
+
+		done := c.inflight.add()
+		defer done()
+
+		options := message.publishOptions(time.Now())
+		return waitToken(ctx, c.mqttClient.Publish(message.Topic.String(), options.qos, options.retain, message.Payload))
+	})
+	if err != nil {
+		c.obs.Log().Warn("Unable to drain the offline queue", zap.Error(err))
+	}
+}
+
+// PublishRPC is not supported, as MQTT v3 has no response topic and correlation data
+func (c *mqttV3) PublishRPC(_ context.Context, _ Topic, _ interface{}, _ ...PublishOpt) ([]byte, error) {
+	return nil, ErrRPCNotSupported
+}
+
+// SubscribeRPC is not supported, as MQTT v3 has no response topic and correlation data
+func (c *mqttV3) SubscribeRPC(_ context.Context, _ Topic, _ RequestHandler, _ ...SubscribeOpt) error {
+	return ErrRPCNotSupported
+}
+
+// waitToken waits until the token completes or the context is done
+func waitToken(ctx context.Context, token mqtt.Token) error {
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // SubscribeWithId to a topic
-func (c *mqttV3) SubscribeWithId(_ context.Context, topic Topic, handler Handler) {
+func (c *mqttV3) SubscribeWithId(_ context.Context, topic Topic, handler Handler, opts ...SubscribeOpt) {
 	logInfo := c.obs.Log().With(zap.String("topic", string(topic)))
 	logInfo.Debug("Subscribing to a topic")
 
-	token := c.mqttClient.Subscribe(topic.String(), 1, func(client mqtt.Client, message mqtt.Message) {
-		var data interface{}
+	c.subscribeAsync(topic, c.idsMessageHandler(topic, handler, newSubscribeOptions(opts...)))
+}
+
+// SubscribeShared subscribes to a topic as a member of the shared subscription group, so each message is delivered to
+// only one of the group members. Requires a broker supporting shared subscriptions in MQTT v3.
+// The handler receives the values of the topic wildcards as ids.
+func (c *mqttV3) SubscribeShared(ctx context.Context, group string, topic Topic, handler Handler, opts ...SubscribeOpt) error {
+	sharedTopic, err := SharedTopic(group, topic)
+	if err != nil {
+		return err
+	}
+
+	logInfo := c.obs.Log().With(zap.String("topic", sharedTopic.String()))
+	logInfo.Debug("Subscribing to a shared topic")
+
+	return c.subscribe(ctx, sharedTopic, c.idsMessageHandler(topic, handler, newSubscribeOptions(opts...)))
+}
+
+// idsMessageHandler decodes the payload and passes the values of the topic wildcards to the handler
+func (c *mqttV3) idsMessageHandler(topic Topic, handler Handler, options *SubscribeOptions) func(ctx context.Context, message mqtt.Message) {
+	logInfo := c.obs.Log().With(zap.String("topic", string(topic)))
+	codec := options.codecOrDefault(c.codec)
 
-		// todo support other types of messages
+	return func(ctx context.Context, message mqtt.Message) {
 		// Transform the payload to the object and pass it to the handler function for further processing
-		err := json.Unmarshal(message.Payload(), &data)
+		data, err := options.decode(codec, message.Payload())
 		if err != nil {
 			logInfo.Sugar().Errorf("Error parsing the data: %v", err)
+			c.decodeFailed(ctx, topic, err)
 
 			// Invoke handler with error
 			handler(c, nil, message.MessageID(), nil, err)
@@ -125,6 +407,7 @@ func (c *mqttV3) SubscribeWithId(_ context.Context, topic Topic, handler Handler
 		ids, err := GetIdsFromTopic(c.obs.Log(), message.Topic(), topic)
 		if err != nil {
 			logInfo.Sugar().Errorf("Error getting the topic info: %v", err)
+			recordSpanError(ctx, err)
 
 			// Invoke handler with error
 			handler(c, nil, message.MessageID(), nil, err)
@@ -132,46 +415,146 @@ func (c *mqttV3) SubscribeWithId(_ context.Context, topic Topic, handler Handler
 		}
 
 		handler(c, ids, message.MessageID(), data, err)
-	})
-
-	go func(token mqtt.Token) {
-		token.Wait()
-		if token.Error() != nil {
-			logInfo.Warn("Token error", zap.Error(token.Error()))
-		}
-	}(token)
+	}
 }
 
 // Subscribe to a topic
-func (c *mqttV3) Subscribe(_ context.Context, topic Topic, handler Handler) error {
+func (c *mqttV3) Subscribe(_ context.Context, topic Topic, handler Handler, opts ...SubscribeOpt) error {
 	logInfo := c.obs.Log().With(zap.String("topic", string(topic)))
 	logInfo.Debug("Subscribing to a topic")
 
-	token := c.mqttClient.Subscribe(topic.String(), 1, func(client mqtt.Client, message mqtt.Message) {
-		var data interface{}
+	options := newSubscribeOptions(opts...)
+	codec := options.codecOrDefault(c.codec)
 
+	c.subscribeAsync(topic, func(ctx context.Context, message mqtt.Message) {
 		// Transform the payload to the object and pass it to the handler function for further processing
-		err := json.Unmarshal(message.Payload(), &data)
+		data, err := options.decode(codec, message.Payload())
 		if err != nil {
 			logInfo.Sugar().Errorf("Error parsing the data: %v", err)
+			c.decodeFailed(ctx, topic, err)
 			return
 		}
 
 		ids := strings.Split(message.Topic(), "/")
 		handler(c, ids, message.MessageID(), data, err)
 	})
+	return nil
+}
+
+// envelopedMessage is a received message with the payload unwrapped from the trace envelope
+type envelopedMessage struct {
+	mqtt.Message
+	payload []byte
+}
+
+func (m envelopedMessage) Payload() []byte {
+	return m.payload
+}
+
+// instrument wraps the handler with the consumer span, continuing the trace of the publisher if the trace envelope is
+// enabled, and records the received message metrics
+func (c *mqttV3) instrument(topic Topic, handler func(ctx context.Context, message mqtt.Message)) mqtt.MessageHandler {
+	filter := stripSharePrefix(topic)
+
+	return func(_ mqtt.Client, message mqtt.Message) {
+		ctx := context.Background()
+		if c.traceEnvelope {
+			var payload []byte
+			ctx, payload = unwrapTraceEnvelope(ctx, message.Payload())
+			message = envelopedMessage{Message: message, payload: payload}
+		}
+
+		ctx, endSpan := c.consumer.Span(ctx, "process "+filter.String(),
+			processSpanFields(c.id, message.Topic(), filter, message.MessageID(), len(message.Payload()))...,
+		)
+		defer endSpan()
+
+		start := time.Now()
+		handler(ctx, message)
+		c.metrics.RecordMessageReceived(filter, time.Since(start))
+	}
+}
+
+// decodeFailed records the payload of a received message that could not be decoded
+func (c *mqttV3) decodeFailed(ctx context.Context, topic Topic, err error) {
+	recordSpanError(ctx, err)
+	c.metrics.IncrementDecodeFailures(stripSharePrefix(topic))
+}
+
+// subscribe registers the subscription and waits for the broker acknowledgement or the context to be done.
+// If the client is not connected, the subscription is made once the connection is up.
+func (c *mqttV3) subscribe(ctx context.Context, topic Topic, messageHandler func(ctx context.Context, message mqtt.Message)) error {
+	handler := c.instrument(topic, messageHandler)
+	c.subscriptions.add(topic, 1, handler)
+
+	if !c.mqttClient.IsConnectionOpen() {
+		return nil
+	}
+
+	err := waitToken(ctx, c.mqttClient.Subscribe(topic.String(), 1, handler))
+	c.subscriptions.setResult(topic, err)
+	return err
+}
+
+// subscribeAsync registers the subscription and logs the result of the subscription without waiting for it
+func (c *mqttV3) subscribeAsync(topic Topic, messageHandler func(ctx context.Context, message mqtt.Message)) {
+	handler := c.instrument(topic, messageHandler)
+	c.subscriptions.add(topic, 1, handler)
+
+	if !c.mqttClient.IsConnectionOpen() {
+		return
+	}
 
 	go func(token mqtt.Token) {
 		token.Wait()
+		c.subscriptions.setResult(topic, token.Error())
 		if token.Error() != nil {
-			c.obs.Log().Warn("Token error", zap.Error(token.Error()))
+			c.obs.Log().Warn("Token error", zap.Error(token.Error()), zap.String("topic", topic.String()))
 		}
-	}(token)
-	return nil
+	}(c.mqttClient.Subscribe(topic.String(), 1, handler))
+}
+
+// resubscribe subscribes to all the registered topics after a (re)connection
+func (c *mqttV3) resubscribe() {
+	subscriptions := c.subscriptions.all()
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resubscribeTimeout)
+	defer cancel()
+
+	failed := 0
+	for _, sub := range subscriptions {
+		err := waitToken(ctx, c.mqttClient.Subscribe(sub.info.Topic.String(), sub.info.QoS, sub.handler))
+		c.subscriptions.setResult(sub.info.Topic, err)
+		if err != nil {
+			failed++
+			c.obs.Log().Warn("Unable to restore a subscription", zap.Error(err), zap.String("topic", sub.info.Topic.String()))
+		}
+	}
+
+	c.obs.Log().Debug("Restored the subscriptions", zap.Int("subscriptions", len(subscriptions)), zap.Int("failed", failed))
+}
+
+// Subscriptions returns the state of the subscriptions
+func (c *mqttV3) Subscriptions() []SubscriptionInfo {
+	return c.subscriptions.list()
+}
+
+// ConnectionState returns the current state of the connection
+func (c *mqttV3) ConnectionState() ConnectionState {
+	return c.state.get()
+}
+
+// OnConnectionStateChange registers a handler called whenever the connection state changes
+func (c *mqttV3) OnConnectionStateChange(handler ConnectionStateHandler) {
+	c.state.addHandler(handler)
 }
 
+// Pass reports whether the connection is up. The v3 library also reports a reconnecting client as connected.
 func (c *mqttV3) Pass() bool {
-	return c.mqttClient.IsConnected()
+	return c.mqttClient.IsConnectionOpen()
 }
 
 func (c *mqttV3) Name() string {
@@ -0,0 +1,51 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeToken is a token completed by closing the done channel
+type fakeToken struct {
+	done chan struct{}
+	err  error
+}
+
+func (t *fakeToken) Wait() bool {
+	<-t.done
+	return true
+}
+
+func (t *fakeToken) WaitTimeout(timeout time.Duration) bool {
+	select {
+	case <-t.done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (t *fakeToken) Done() <-chan struct{} {
+	return t.done
+}
+
+func (t *fakeToken) Error() error {
+	return t.err
+}
+
+func TestWaitToken(t *testing.T) {
+	errNotAuthorized := errors.New("not authorized")
+
+	token := &fakeToken{done: make(chan struct{}), err: errNotAuthorized}
+	close(token.done)
+	assert.ErrorIs(t, waitToken(context.Background(), token), errNotAuthorized)
+
+	// The context is honored while the acknowledgement is pending
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, waitToken(ctx, &fakeToken{done: make(chan struct{})}), context.DeadlineExceeded)
+}
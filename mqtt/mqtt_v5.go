@@ -2,52 +2,173 @@ package mqtt
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/tls"
 	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	mqtt "github.com/eclipse/paho.golang/autopaho"
 	"github.com/eclipse/paho.golang/paho"
+	"github.com/pkg/errors"
 	"github.com/xBlaz3kx/DevX/observability"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+// resubscribeTimeout is the time limit for restoring the subscriptions after a (re)connection
+const resubscribeTimeout = 10 * time.Second
+
 // mqttV5 concrete implementation of the ClientV5, which is essentially a wrapper over the mqtt lib.
 type mqttV5 struct {
-	mqttClient *mqtt.ConnectionManager
+	// mqttClient is the connection manager between Connect and Disconnect, nil otherwise
+	mqttClient atomic.Pointer[mqtt.ConnectionManager]
 	brokerUrl  *url.URL
+	tlsConfig  *tls.Config
 	clientId   string
+	config     Configuration
+	codec      Codec
 	obs        observability.Observability
+
+	// producer and consumer create the spans of the published and received messages
+	producer observability.Observability
+	consumer observability.Observability
+
+	// router dispatches the received messages to the handlers of the matching subscriptions
+	router *paho.StandardRouter
+
+	// subscriptions are the topic filters subscribed to on every (re)connection
+	subscriptions *subscriptionRegistry[paho.MessageHandler]
+
+	// connection is the connection manager while the connection is up, nil otherwise
+	connection atomic.Pointer[mqtt.ConnectionManager]
+
+	// queue keeps the messages published while disconnected, nil if disabled
+	queue   *offlineQueue
+	metrics mqttMetrics
+
+	state    *connectionState
+	inflight inflightMessages
+
+	// responseTopic is the topic the responses to the requests of this client are published to
+	responseTopic       Topic
+	pending             *pendingRequests[*paho.Publish]
+	responsesMu         sync.Mutex
+	responsesSubscribed bool
 }
 
 // NewV5Client creates a wrapped mqtt ClientV5 with specific settings.
 func NewV5Client(clientSettings Configuration, obs observability.Observability) (Client, error) {
 	obs.Log().Info("Creating a new MQTT client..")
 
-	parse, err := url.Parse(clientSettings.Address)
+	clientSettings = clientSettings.withDefaults()
+	if err := clientSettings.validateSession(); err != nil {
+		return nil, err
+	}
+
+	if err := clientSettings.validatePresence(); err != nil {
+		return nil, err
+	}
+
+	brokerUrl, err := clientSettings.brokerUrl()
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := clientSettings.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	codec, err := CodecByName(clientSettings.Codec)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := newMqttMetrics()
+	if err != nil {
+		return nil, err
+	}
+
+	queue, err := newClientQueue(clientSettings, metrics)
 	if err != nil {
 		return nil, err
 	}
 
 	return &mqttV5{
-		mqttClient: nil,
-		brokerUrl:  parse,
-		clientId:   clientSettings.ClientId,
-		obs:        obs,
+		brokerUrl:     brokerUrl,
+		tlsConfig:     tlsConfig,
+		clientId:      clientSettings.ClientId,
+		config:        clientSettings,
+		codec:         codec,
+		obs:           obs,
+		producer:      obs.WithSpanKind(trace.SpanKindProducer),
+		consumer:      obs.WithSpanKind(trace.SpanKindConsumer),
+		router:        paho.NewStandardRouter(),
+		subscriptions: newSubscriptionRegistry[paho.MessageHandler](),
+		responseTopic: newResponseTopic(clientSettings.ClientId),
+		pending:       newPendingRequests[*paho.Publish](),
+		queue:         queue,
+		metrics:       metrics,
+		state:         newConnectionState(),
 	}, nil
 }
 
+// Connect connects to the broker and waits for the connection or the context to be done. If the context is done
+// first, the connection attempts are stopped and the error of the last attempt is returned.
 func (c *mqttV5) Connect(ctx context.Context) error {
 	c.obs.Log().Debug("Connecting to the broker")
+	c.state.set(ConnectionStateConnecting, nil)
 
 	clientConfig := mqtt.ClientConfig{
-		BrokerUrls: []*url.URL{c.brokerUrl},
-		KeepAlive:  10,
+		ServerUrls:                    []*url.URL{c.brokerUrl},
+		TlsCfg:                        c.tlsConfig,
+		KeepAlive:                     uint16(c.config.KeepAlive.Seconds()),
+		CleanStartOnInitialConnection: !c.config.PersistentSession,
+		SessionExpiryInterval:         uint32(c.config.SessionExpiry.Seconds()),
+		ConnectTimeout:                c.config.ConnectTimeout,
+		ReconnectBackoff:              c.config.ReconnectBackoff.delay,
+		ConnectUsername:               c.config.Username,
+		ConnectPassword:               []byte(c.config.Password),
 		OnConnectionUp: func(cm *mqtt.ConnectionManager, connAck *paho.Connack) {
 			c.obs.Log().Debug("Client connected to broker")
+			c.connection.Store(cm)
+
+			// Disconnect waits for the birth message, so it is not published after the death message
+			birthPublished := c.inflight.add()
+			c.state.update(ConnectionStateConnected, nil)
+
+			// The callback must not block
+			go func() {
+				c.publishBirth(cm)
+				birthPublished()
+				c.resubscribe(cm)
+				c.drainQueue(cm)
+			}()
+		},
+		OnConnectionDown: func() bool {
+			c.obs.Log().Debug("Client disconnected from broker")
+			c.connection.Store(nil)
+			c.subscriptions.setPending()
+			c.state.update(ConnectionStateConnectionLost, nil)
+
+			// Keep reconnecting
+			c.state.update(ConnectionStateReconnecting, nil)
+			return true
+		},
+		OnConnectError: func(err error) {
+			c.obs.Log().With(zap.Error(err)).Debug("error whilst attempting connection")
+			c.state.connectFailed(err)
 		},
-		OnConnectError: func(err error) { c.obs.Log().With(zap.Error(err)).Debug("error whilst attempting connection") },
 		ClientConfig: paho.ClientConfig{
-			ClientID:      c.clientId,
+			ClientID: c.clientId,
+			OnPublishReceived: []func(paho.PublishReceived) (bool, error){
+				func(received paho.PublishReceived) (bool, error) {
+					c.router.Route(received.Packet.Packet())
+					return true, nil
+				},
+			},
 			OnClientError: func(err error) { c.obs.Log().With(zap.Error(err)).Error("server requested disconnect") },
 			OnServerDisconnect: func(d *paho.Disconnect) {
 				if d.Properties != nil {
@@ -57,97 +178,434 @@ func (c *mqttV5) Connect(ctx context.Context) error {
 		},
 	}
 
-	cm, err := mqtt.NewConnection(ctx, clientConfig)
+	if c.config.LastWill != nil {
+		clientConfig.WillMessage, clientConfig.WillProperties = c.config.LastWill.pahoWill()
+	}
+
+	// The connection manager keeps reconnecting until Disconnect, so it must outlive the context of Connect
+	cm, err := mqtt.NewConnection(context.Background(), clientConfig)
 	if err != nil {
-		return err
+		c.state.set(ConnectionStateDisconnected, err)
+		return errors.Wrap(err, "failed to connect to the broker")
 	}
 
-	c.mqttClient = cm
-	return nil
+	c.mqttClient.Store(cm)
+
+	// Wait for the connection callback, which runs after the connection is reported up
+	err = cm.AwaitConnection(ctx)
+	if err == nil {
+		err = c.state.waitFor(ctx, ConnectionStateConnected)
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	// Stop connecting, so the client is in the same state as before Connect
+	stopCtx, cancel := context.WithTimeout(context.Background(), c.config.ConnectTimeout)
+	defer cancel()
+
+	_ = cm.Disconnect(stopCtx)
+	c.mqttClient.Store(nil)
+
+	if connectErr := c.state.lastConnectError(); connectErr != nil {
+		err = connectErr
+	}
+
+	c.state.set(ConnectionStateDisconnected, err)
+	return errors.Wrap(err, "failed to connect to the broker")
 }
 
+// Disconnect waits for the messages in flight until the context is done, publishes the death message and
+// disconnects. Returns the context error if the messages in flight were not acknowledged in time.
 func (c *mqttV5) Disconnect(ctx context.Context) error {
 	c.obs.Log().Debug("Disconnecting the MQTT client")
 
-	err := c.mqttClient.Disconnect(ctx)
+	inflightErr := c.inflight.wait(ctx)
+	if inflightErr != nil {
+		c.obs.Log().Warn("Disconnecting with messages in flight", zap.Error(inflightErr))
+		inflightErr = errors.Wrap(inflightErr, "messages in flight were not acknowledged")
+	}
+
+	// The connection manager does not report the connection down on Disconnect
+	if cm := c.connection.Swap(nil); cm != nil && c.config.DeathMessage != nil {
+		c.publishPresence(ctx, cm, c.config.DeathMessage)
+	}
+
+	c.state.set(ConnectionStateDisconnected, nil)
+
+	if cm := c.mqttClient.Swap(nil); cm != nil {
+		// The context may be done already, the connection is closed regardless
+		disconnectCtx, cancel := context.WithTimeout(context.Background(), c.config.ConnectTimeout)
+		defer cancel()
+
+		if err := cm.Disconnect(disconnectCtx); err != nil {
+			return err
+		}
+	}
+
+	if c.queue != nil {
+		if err := c.queue.close(); err != nil {
+			return err
+		}
+	}
+
+	return inflightErr
+}
+
+// publishBirth publishes the birth message after a (re)connection
+func (c *mqttV5) publishBirth(cm *mqtt.ConnectionManager) {
+	if c.config.BirthMessage == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.ConnectTimeout)
+	defer cancel()
+
+	c.publishPresence(ctx, cm, c.config.BirthMessage)
+}
+
+// publishPresence publishes the presence message. Failures are logged, as they must not prevent (dis)connecting.
+func (c *mqttV5) publishPresence(ctx context.Context, cm *mqtt.ConnectionManager, message *PresenceMessage) {
+	_, err := cm.Publish(ctx, newPahoPublish(message.Topic, []byte(message.Payload), message.publishOptions()))
 	if err != nil {
-		return err
+		c.obs.Log().Warn("Unable to publish the presence message", zap.Error(err), zap.String("topic", message.Topic.String()))
 	}
+}
 
-	return nil
+// Publish a new message to a topic. The message is published with QoS 0 by default.
+// With QoS 1 or 2, it waits for the broker acknowledgement or the context to be done.
+// The trace context is propagated in the user properties of the message.
+func (c *mqttV5) Publish(ctx context.Context, topic Topic, message interface{}, opts ...PublishOpt) error {
+	options := newPublishOptions(0, opts...)
+	filter := options.metricsFilter(topic)
+
+	ctx, endSpan := c.producer.Span(ctx, "publish "+filter, publishSpanFields(c.clientId, topic, filter)...)
+	defer endSpan()
+
+	start := time.Now()
+	err := c.publish(ctx, topic, message, options)
+	recordSpanError(ctx, err)
+	c.metrics.RecordMessagePublished(filter, time.Since(start), err)
+
+	return err
 }
 
-// Publish a new message to a topic
-func (c *mqttV5) Publish(ctx context.Context, topic Topic, message interface{}) error {
+func (c *mqttV5) publish(ctx context.Context, topic Topic, message interface{}, options *PublishOptions) error {
+	cm := c.mqttClient.Load()
+	if cm == nil && c.queue == nil {
+		return ErrNotConnected
+	}
+
 	logInfo := c.obs.Log().With(
 		zap.String("topic", string(topic)),
 		zap.Any("message", message),
+		zap.Uint8("qos", options.qos),
 	)
 	logInfo.Debug("Publishing a message to topic")
 
-	payload, err := json.Marshal(message)
+	payload, err := options.encode(c.codec, message)
 	if err != nil {
 		return err
 	}
 
-	publishMessage := paho.Publish{
-		QoS:     0,
-		Retain:  false,
-		Topic:   topic.String(),
-		Payload: payload,
-		Properties: &paho.PublishProperties{
-			CorrelationData:        nil,
-			ContentType:            "application/json",
-			ResponseTopic:          "",
-			PayloadFormat:          nil,
-			MessageExpiry:          nil,
-			SubscriptionIdentifier: nil,
-			TopicAlias:             nil,
-			User:                   nil,
-		},
+	injectTraceProperties(ctx, options)
+
+	// Keep the order of the queued messages until the queue is drained
+	if c.queue != nil && (cm == nil || c.connection.Load() == nil || c.queue.len() > 0) {
+		return c.enqueue(newQueuedMessage(topic, payload, options))
+	}
+
+	done := c.inflight.add()
+	_, err = cm.Publish(ctx, newPahoPublish(topic, payload, options))
+	done()
+
+	if c.queue != nil && errors.Is(err, mqtt.ConnectionDownError) {
+		return c.enqueue(newQueuedMessage(topic, payload, options))
 	}
 
-	_, err = c.mqttClient.Publish(ctx, &publishMessage)
 	return err
 }
 
-// Subscribe to a topic
-func (c *mqttV5) Subscribe(ctx context.Context, topic Topic, handler Handler) error {
+// enqueue queues the message until the client is connected. If the client is connected, the queue is drained.
+func (c *mqttV5) enqueue(message queuedMessage) error {
+	c.obs.Log().Debug("Queueing the message until the client is connected", zap.String("topic", message.Topic.String()))
+
+	err := c.queue.enqueue(message)
+	if err != nil {
+		return err
+	}
+
+	if cm := c.connection.Load(); cm != nil {
+		go c.drainQueue(cm)
+	}
+
+	return nil
+}
+
+// drainQueue publishes the queued messages in order
+func (c *mqttV5) drainQueue(cm *mqtt.ConnectionManager) {
+	if c.queue == nil {
+		return
+	}
+
+	err := c.queue.drain(func(message queuedMessage) error {
+		ctx, cancel := context.WithTimeout(context.Background(), drainPublishTimeout)
+		defer cancel()
+
+		done := c.inflight.add()
+		defer done()
+
+		_, err := cm.Publish(ctx, newPahoPublish(message.Topic, message.Payload, message.publishOptions(time.Now())))
+		return err
+	})
+	if err != nil {
+		c.obs.Log().Warn("Unable to drain the offline queue", zap.Error(err))
+	}
+}
+
+// newPahoPublish creates the publish packet with the properties from the options
+func newPahoPublish(topic Topic, payload []byte, options *PublishOptions) *paho.Publish {
+	properties := &paho.PublishProperties{
+		ContentType: options.contentType,
+	}
+
+	if options.messageExpiry > 0 {
+		expiry := uint32(max(options.messageExpiry, time.Second).Seconds())
+		properties.MessageExpiry = &expiry
+	}
+
+	if options.topicAlias > 0 {
+		alias := options.topicAlias
+		properties.TopicAlias = &alias
+	}
+
+	for _, property := range options.userProperties {
+		properties.User.Add(property.Key, property.Value)
+	}
+
+	return &paho.Publish{
+		QoS:        options.qos,
+		Retain:     options.retain,
+		Topic:      topic.String(),
+		Payload:    payload,
+		Properties: properties,
+	}
+}
+
+// Subscribe to a topic. The handler receives the levels of the actual topic as ids.
+func (c *mqttV5) Subscribe(ctx context.Context, topic Topic, handler Handler, opts ...SubscribeOpt) error {
 	logInfo := c.obs.Log().With(
 		zap.String("topic", topic.String()),
 	)
 	logInfo.Debug("Subscribing to a topic")
 
-	subscribe := paho.Subscribe{
-		Properties: &paho.SubscribeProperties{
-			SubscriptionIdentifier: nil,
-			User:                   nil,
-		},
-		Subscriptions: nil,
+	options := newSubscribeOptions(opts...)
+
+	return c.subscribe(ctx, topic, func(ctx context.Context, message *paho.Publish) {
+		// Transform the payload to the object and pass it to the handler function for further processing
+		data, err := options.decode(c.messageCodec(message, options), message.Payload)
+		if err != nil {
+			logInfo.Sugar().Errorf("Error parsing the data: %v", err)
+			c.decodeFailed(ctx, topic, err)
+			return
+		}
+
+		ids := strings.Split(message.Topic, "/")
+		handler(c, ids, message.PacketID, data, err)
+	})
+}
+
+// SubscribeWithId to a topic. The handler receives the values of the topic wildcards as ids.
+func (c *mqttV5) SubscribeWithId(ctx context.Context, topic Topic, handler Handler, opts ...SubscribeOpt) {
+	logInfo := c.obs.Log().With(
+		zap.String("topic", string(topic)),
+	)
+	logInfo.Debug("Subscribing to a topic")
+
+	err := c.subscribe(ctx, topic, c.idsMessageHandler(topic, handler, newSubscribeOptions(opts...)))
+	if err != nil {
+		logInfo.Warn("Unable to subscribe to a topic", zap.Error(err))
 	}
+}
 
-	_, err := c.mqttClient.Subscribe(ctx, &subscribe)
+// SubscribeShared subscribes to a topic as a member of the shared subscription group, so each message is delivered to
+// only one of the group members. The handler receives the values of the topic wildcards as ids.
+func (c *mqttV5) SubscribeShared(ctx context.Context, group string, topic Topic, handler Handler, opts ...SubscribeOpt) error {
+	sharedTopic, err := SharedTopic(group, topic)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	logInfo := c.obs.Log().With(
+		zap.String("topic", sharedTopic.String()),
+	)
+	logInfo.Debug("Subscribing to a shared topic")
+
+	// The router strips the share prefix when matching the received topics
+	return c.subscribe(ctx, sharedTopic, c.idsMessageHandler(topic, handler, newSubscribeOptions(opts...)))
 }
 
-// SubscribeWithId to a topic
-func (c *mqttV5) SubscribeWithId(ctx context.Context, topic Topic, handler Handler) {
+// idsMessageHandler decodes the payload and passes the values of the topic wildcards to the handler
+func (c *mqttV5) idsMessageHandler(topic Topic, handler Handler, options *SubscribeOptions) func(ctx context.Context, message *paho.Publish) {
 	logInfo := c.obs.Log().With(
 		zap.String("topic", string(topic)),
 	)
-	logInfo.Debug("Subscribing to a topic")
+
+	return func(ctx context.Context, message *paho.Publish) {
+		// Transform the payload to the object and pass it to the handler function for further processing
+		data, err := options.decode(c.messageCodec(message, options), message.Payload)
+		if err != nil {
+			logInfo.Sugar().Errorf("Error parsing the data: %v", err)
+			c.decodeFailed(ctx, topic, err)
+
+			// Invoke handler with error
+			handler(c, nil, message.PacketID, nil, err)
+			return
+		}
+
+		// Parse the topic and get the Ids based on the original topic.
+		ids, err := GetIdsFromTopic(c.obs.Log(), message.Topic, topic)
+		if err != nil {
+			logInfo.Sugar().Errorf("Error getting the topic info: %v", err)
+			recordSpanError(ctx, err)
+
+			// Invoke handler with error
+			handler(c, nil, message.PacketID, nil, err)
+			return
+		}
+
+		handler(c, ids, message.PacketID, data, err)
+	}
+}
+
+// messageCodec returns the codec of the message content type, falling back to the codec of the subscription or the client
+func (c *mqttV5) messageCodec(message *paho.Publish, options *SubscribeOptions) Codec {
+	if message.Properties != nil {
+		codec, ok := codecByContentType(message.Properties.ContentType, options.codec, c.codec)
+		if ok {
+			return codec
+		}
+	}
+
+	return options.codecOrDefault(c.codec)
+}
+
+// instrument wraps the handler with the consumer span, continuing the trace of the publisher, and records the
+// received message metrics
+func (c *mqttV5) instrument(topic Topic, handler func(ctx context.Context, message *paho.Publish)) paho.MessageHandler {
+	filter := stripSharePrefix(topic)
+
+	return func(message *paho.Publish) {
+		ctx := extractTraceProperties(context.Background(), message)
+		ctx, endSpan := c.consumer.Span(ctx, "process "+filter.String(),
+			processSpanFields(c.clientId, message.Topic, filter, message.PacketID, len(message.Payload))...,
+		)
+		defer endSpan()
+
+		start := time.Now()
+		handler(ctx, message)
+		c.metrics.RecordMessageReceived(filter, time.Since(start))
+	}
+}
+
+// decodeFailed records the payload of a received message that could not be decoded
+func (c *mqttV5) decodeFailed(ctx context.Context, topic Topic, err error) {
+	recordSpanError(ctx, err)
+	c.metrics.IncrementDecodeFailures(stripSharePrefix(topic))
+}
+
+// subscribe registers the message handler for the topic filter and subscribes to it. If the client is not connected,
+// the subscription is made once the connection is up.
+func (c *mqttV5) subscribe(ctx context.Context, topic Topic, handler func(ctx context.Context, message *paho.Publish)) error {
+	options := paho.SubscribeOptions{Topic: topic.String(), QoS: 1}
+	messageHandler := c.instrument(topic, handler)
+
+	// Replace the handler of an existing subscription
+	c.router.UnregisterHandler(topic.String())
+	c.router.RegisterHandler(topic.String(), messageHandler)
+	c.subscriptions.add(topic, options.QoS, messageHandler)
+
+	cm := c.mqttClient.Load()
+	if cm == nil {
+		return nil
+	}
+
+	suback, err := cm.Subscribe(ctx, &paho.Subscribe{Subscriptions: []paho.SubscribeOptions{options}})
+	if errors.Is(err, mqtt.ConnectionDownError) {
+		// Restored when the connection is up
+		return nil
+	}
+
+	err = subackResult(suback, 0, err)
+	c.subscriptions.setResult(topic, err)
+	return err
+}
+
+// resubscribe subscribes to all the topic filters after a (re)connection
+func (c *mqttV5) resubscribe(cm *mqtt.ConnectionManager) {
+	subscriptions := c.subscriptions.all()
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	options := make([]paho.SubscribeOptions, len(subscriptions))
+	for i, sub := range subscriptions {
+		options[i] = paho.SubscribeOptions{Topic: sub.info.Topic.String(), QoS: sub.info.QoS}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resubscribeTimeout)
+	defer cancel()
+
+	suback, err := cm.Subscribe(ctx, &paho.Subscribe{Subscriptions: options})
+	for i, sub := range subscriptions {
+		c.subscriptions.setResult(sub.info.Topic, subackResult(suback, i, err))
+	}
+
+	if err != nil {
+		c.obs.Log().Error("Unable to restore the subscriptions", zap.Error(err), zap.Int("subscriptions", len(subscriptions)))
+		return
+	}
+
+	c.obs.Log().Debug("Restored the subscriptions", zap.Int("subscriptions", len(subscriptions)))
+}
+
+// subackResult returns the result of the i-th subscription of the subscribe request. The reason codes of the suback
+// tell which subscriptions failed when subscribing to multiple topics.
+func subackResult(suback *paho.Suback, i int, err error) error {
+	if suback == nil || i >= len(suback.Reasons) {
+		return err
+	}
+
+	if suback.Reasons[i] >= 0x80 {
+		return errors.Errorf("subscription rejected with reason code %#x", suback.Reasons[i])
+	}
+
+	return nil
+}
+
+// Subscriptions returns the state of the subscriptions
+func (c *mqttV5) Subscriptions() []SubscriptionInfo {
+	return c.subscriptions.list()
 }
 
 func (c *mqttV5) GetId() string {
 	return c.clientId
 }
 
+// ConnectionState returns the current state of the connection
+func (c *mqttV5) ConnectionState() ConnectionState {
+	return c.state.get()
+}
+
+// OnConnectionStateChange registers a handler called whenever the connection state changes
+func (c *mqttV5) OnConnectionStateChange(handler ConnectionStateHandler) {
+	c.state.addHandler(handler)
+}
+
+// Pass reports whether the connection is up
 func (c *mqttV5) Pass() bool {
-	return true
+	return c.state.get() == ConnectionStateConnected
 }
 
 func (c *mqttV5) Name() string {
@@ -0,0 +1,135 @@
+package mqtt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xBlaz3kx/DevX/observability"
+)
+
+type receivedMessage struct {
+	ids       []string
+	payloadId uint16
+	payload   interface{}
+	err       error
+}
+
+func newTestV5Client(t *testing.T) *mqttV5 {
+	t.Helper()
+
+	client, err := NewV5Client(Configuration{Address: "mqtt://localhost:1883", ClientId: "test"}, observability.NewNoopObservability())
+	require.NoError(t, err)
+
+	return client.(*mqttV5)
+}
+
+func routeMessage(client *mqttV5, topic string, payload string) {
+	client.router.Route((&paho.Publish{PacketID: 7, QoS: 1, Topic: topic, Payload: []byte(payload), Properties: &paho.PublishProperties{}}).Packet())
+}
+
+func TestV5SubscribeWithId(t *testing.T) {
+	client := newTestV5Client(t)
+
+	var received []receivedMessage
+	client.SubscribeWithId(context.Background(), "devices/+/status/+", func(_ Client, ids []string, payloadId uint16, payload interface{}, err error) {
+		received = append(received, receivedMessage{ids: ids, payloadId: payloadId, payload: payload, err: err})
+	})
+
+	// The subscription is kept for when the client connects
+	assert.Contains(t, subscribedTopics(client), Topic("devices/+/status/+"))
+
+	routeMessage(client, "devices/device1/status/connector2", `{"status":"online"}`)
+	routeMessage(client, "devices/device1/status/connector2", `not json`)
+	routeMessage(client, "other/device1", `{}`)
+
+	require.Len(t, received, 2)
+	assert.Equal(t, []string{"device1", "connector2"}, received[0].ids)
+	assert.Equal(t, uint16(7), received[0].payloadId)
+	assert.Equal(t, map[string]interface{}{"status": "online"}, received[0].payload)
+	assert.NoError(t, received[0].err)
+
+	// Invalid payloads are passed to the handler as errors
+	assert.Error(t, received[1].err)
+	assert.Nil(t, received[1].payload)
+}
+
+func TestV5SubscribeShared(t *testing.T) {
+	client := newTestV5Client(t)
+
+	var received []receivedMessage
+	err := client.SubscribeShared(context.Background(), "ingestion", "devices/+/status", func(_ Client, ids []string, payloadId uint16, payload interface{}, err error) {
+		received = append(received, receivedMessage{ids: ids, payloadId: payloadId, payload: payload, err: err})
+	})
+	require.NoError(t, err)
+	assert.Contains(t, subscribedTopics(client), Topic("$share/ingestion/devices/+/status"))
+
+	// The broker delivers the messages with the topic they were published to
+	routeMessage(client, "devices/device1/status", `"online"`)
+
+	require.Len(t, received, 1)
+	assert.Equal(t, []string{"device1"}, received[0].ids)
+	assert.Equal(t, "online", received[0].payload)
+	assert.NoError(t, received[0].err)
+
+	assert.ErrorIs(t, client.SubscribeShared(context.Background(), "", "devices/+/status", nil), ErrInvalidShareGroup)
+}
+
+func TestV5Subscribe(t *testing.T) {
+	client := newTestV5Client(t)
+
+	var received []receivedMessage
+	handler := func(_ Client, ids []string, payloadId uint16, payload interface{}, err error) {
+		received = append(received, receivedMessage{ids: ids, payloadId: payloadId, payload: payload, err: err})
+	}
+
+	require.NoError(t, client.Subscribe(context.Background(), "devices/#", handler))
+
+	// Subscribing again to the same filter replaces the handler
+	require.NoError(t, client.Subscribe(context.Background(), "devices/#", handler))
+	assert.Len(t, subscribedTopics(client), 1)
+
+	routeMessage(client, "devices/device1/status", `"online"`)
+
+	require.Len(t, received, 1)
+	assert.Equal(t, []string{"devices", "device1", "status"}, received[0].ids)
+	assert.Equal(t, "online", received[0].payload)
+}
+
+func TestNewPahoPublish(t *testing.T) {
+	options := newPublishOptions(0,
+		WithQoS(5),
+		WithRetain(true),
+		WithMessageExpiry(time.Minute),
+		WithContentType("application/cbor"),
+		WithUserProperty("source", "test"),
+		WithUserProperty("source", "other"),
+		WithTopicAlias(3),
+	)
+
+	publish := newPahoPublish("devices/device1", []byte("payload"), options)
+	assert.Equal(t, byte(2), publish.QoS)
+	assert.True(t, publish.Retain)
+	assert.Equal(t, "devices/device1", publish.Topic)
+	assert.Equal(t, []byte("payload"), publish.Payload)
+	assert.Equal(t, "application/cbor", publish.Properties.ContentType)
+	assert.Equal(t, uint32(60), *publish.Properties.MessageExpiry)
+	assert.Equal(t, uint16(3), *publish.Properties.TopicAlias)
+	assert.Equal(t, []string{"test", "other"}, publish.Properties.User.GetAll("source"))
+
+	// Defaults
+	options = newPublishOptions(0)
+	payload, err := options.encode(JSONCodec, nil)
+	require.NoError(t, err)
+
+	publish = newPahoPublish("devices/device1", payload, options)
+	assert.Equal(t, byte(0), publish.QoS)
+	assert.False(t, publish.Retain)
+	assert.Equal(t, "application/json", publish.Properties.ContentType)
+	assert.Nil(t, publish.Properties.MessageExpiry)
+	assert.Nil(t, publish.Properties.TopicAlias)
+	assert.Empty(t, publish.Properties.User)
+}
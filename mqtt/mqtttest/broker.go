@@ -0,0 +1,89 @@
+// Package mqtttest provides an embedded MQTT broker for integration tests, so the MQTT clients can be tested without
+// containers.
+package mqtttest
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/pkg/errors"
+	"github.com/xBlaz3kx/DevX/mqtt"
+)
+
+// Broker is an in-process MQTT broker listening on a local port. It supports MQTT v3.1.1 and v5, including retained
+// messages, shared subscriptions, last wills and request/response, and allows all clients to connect.
+type Broker struct {
+	server   *mochi.Server
+	listener *listeners.TCP
+}
+
+// NewBroker starts a broker on a free local port
+func NewBroker() (*Broker, error) {
+	server := mochi.New(&mochi.Options{
+		InlineClient: true,
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+
+	err := server.AddHook(new(auth.AllowHook), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to add the auth hook")
+	}
+
+	listener := listeners.NewTCP(listeners.Config{ID: "tcp", Address: "127.0.0.1:0"})
+	err = server.AddListener(listener)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to listen")
+	}
+
+	err = server.Serve()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start the broker")
+	}
+
+	return &Broker{server: server, listener: listener}, nil
+}
+
+// StartBroker starts a broker for the test and closes it when the test ends
+func StartBroker(t testing.TB) *Broker {
+	t.Helper()
+
+	broker, err := NewBroker()
+	if err != nil {
+		t.Fatalf("unable to start the MQTT broker: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = broker.Close()
+	})
+
+	return broker
+}
+
+// Address returns the address the clients connect to, e.g. mqtt://127.0.0.1:41883
+func (b *Broker) Address() string {
+	return fmt.Sprintf("mqtt://%s", b.listener.Address())
+}
+
+// Configuration returns the client configuration for connecting to the broker with the MQTT version and client id
+func (b *Broker) Configuration(version string, clientId string) mqtt.Configuration {
+	return mqtt.Configuration{
+		Version:  version,
+		Address:  b.Address(),
+		ClientId: clientId,
+	}
+}
+
+// Publish publishes a message from the broker itself, e.g. to simulate a device
+func (b *Broker) Publish(topic mqtt.Topic, payload []byte, retain bool, qos byte) error {
+	return b.server.Publish(topic.String(), payload, retain, qos)
+}
+
+// Close disconnects the clients and stops the broker
+func (b *Broker) Close() error {
+	return b.server.Close()
+}
@@ -0,0 +1,135 @@
+package mqtttest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xBlaz3kx/DevX/mqtt"
+	"github.com/xBlaz3kx/DevX/observability"
+)
+
+type received struct {
+	ids     []string
+	payload interface{}
+}
+
+func connect(t *testing.T, config mqtt.Configuration) mqtt.Client {
+	t.Helper()
+
+	client, err := mqtt.NewClientFromConfig(observability.NewNoopObservability(), config)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, client.Connect(ctx))
+	t.Cleanup(func() {
+		_ = client.Disconnect(context.Background())
+	})
+
+	return client
+}
+
+func TestBroker(t *testing.T) {
+	broker := StartBroker(t)
+
+	for _, version := range []string{mqtt.MqttVersion3, mqtt.MqttVersion5} {
+		t.Run(version, func(t *testing.T) {
+			subscriber := connect(t, broker.Configuration(version, "subscriber-"+version))
+			publisher := connect(t, broker.Configuration(version, "publisher-"+version))
+
+			assert.Equal(t, mqtt.ConnectionStateConnected, subscriber.ConnectionState())
+			assert.True(t, subscriber.Pass())
+
+			messages := make(chan received, 1)
+			subscriber.SubscribeWithId(context.Background(), mqtt.Topic(version+"/devices/+/status"), func(_ mqtt.Client, ids []string, _ uint16, payload interface{}, err error) {
+				assert.NoError(t, err)
+				messages <- received{ids: ids, payload: payload}
+			})
+
+			require.Eventually(t, func() bool {
+				for _, sub := range subscriber.Subscriptions() {
+					if sub.State != mqtt.SubscriptionActive {
+						return false
+					}
+				}
+				return true
+			}, 5*time.Second, 10*time.Millisecond)
+
+			err := publisher.Publish(context.Background(), mqtt.Topic(version+"/devices/device1/status"), map[string]string{"status": "online"}, mqtt.WithQoS(1))
+			require.NoError(t, err)
+
+			select {
+			case message := <-messages:
+				assert.Equal(t, []string{"device1"}, message.ids)
+				assert.Equal(t, map[string]interface{}{"status": "online"}, message.payload)
+			case <-time.After(5 * time.Second):
+				t.Fatal("message was not received")
+			}
+		})
+	}
+}
+
+func TestBrokerPublish(t *testing.T) {
+	broker := StartBroker(t)
+
+	// Retained messages are delivered to the later subscribers
+	require.NoError(t, broker.Publish("devices/device1/config", []byte(`{"interval":5}`), true, 1))
+
+	client := connect(t, broker.Configuration(mqtt.MqttVersion5, "client"))
+
+	messages := make(chan received, 1)
+	client.SubscribeWithId(context.Background(), "devices/+/config", func(_ mqtt.Client, ids []string, _ uint16, payload interface{}, err error) {
+		assert.NoError(t, err)
+		messages <- received{ids: ids, payload: payload}
+	})
+
+	select {
+	case message := <-messages:
+		assert.Equal(t, []string{"device1"}, message.ids)
+		assert.Equal(t, map[string]interface{}{"interval": float64(5)}, message.payload)
+	case <-time.After(5 * time.Second):
+		t.Fatal("retained message was not received")
+	}
+}
+
+func TestBrokerPresence(t *testing.T) {
+	broker := StartBroker(t)
+
+	for _, version := range []string{mqtt.MqttVersion3, mqtt.MqttVersion5} {
+		t.Run(version, func(t *testing.T) {
+			monitor := connect(t, broker.Configuration(version, "monitor-"+version))
+
+			statuses := make(chan interface{}, 2)
+			monitor.SubscribeWithId(context.Background(), mqtt.Topic("presence/"+version+"/+"), func(_ mqtt.Client, _ []string, _ uint16, payload interface{}, err error) {
+				assert.NoError(t, err)
+				statuses <- payload
+			})
+			require.Eventually(t, func() bool {
+				return monitor.Subscriptions()[0].State == mqtt.SubscriptionActive
+			}, 5*time.Second, 10*time.Millisecond)
+
+			config := broker.Configuration(version, "gateway-"+version)
+			config.BirthMessage = &mqtt.PresenceMessage{Topic: mqtt.Topic("presence/" + version + "/gateway"), Payload: `"online"`, QoS: 1}
+			config.DeathMessage = &mqtt.PresenceMessage{Topic: mqtt.Topic("presence/" + version + "/gateway"), Payload: `"offline"`, QoS: 1}
+
+			gateway := connect(t, config)
+			assert.True(t, gateway.Pass())
+			require.NoError(t, gateway.Disconnect(context.Background()))
+			assert.Equal(t, mqtt.ConnectionStateDisconnected, gateway.ConnectionState())
+			assert.False(t, gateway.Pass())
+
+			for _, expected := range []string{"online", "offline"} {
+				select {
+				case status := <-statuses:
+					assert.Equal(t, expected, status)
+				case <-time.After(5 * time.Second):
+					t.Fatalf("%s status was not received", expected)
+				}
+			}
+		})
+	}
+}
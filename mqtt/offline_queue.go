@@ -0,0 +1,519 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DropOldest drops the oldest queued messages to make room for the new message, except the message being sent
+	DropOldest = "oldest"
+	// DropNewest rejects the new message when the queue is full
+	DropNewest = "newest"
+
+	defaultOfflineQueueMaxMessages = 10000
+
+	// drainPublishTimeout limits publishing a single queued message
+	drainPublishTimeout = 10 * time.Second
+
+	// maxQueuedLineSize is the maximum size of a queued message in the queue file
+	maxQueuedLineSize = 256 * 1024 * 1024
+
+	dropReasonFull    = "full"
+	dropReasonExpired = "expired"
+)
+
+var (
+	ErrOfflineQueueFull  = errors.New("offline queue is full")
+	ErrInvalidDropPolicy = errors.New("invalid offline queue drop policy")
+)
+
+// OfflineQueue configures the file-backed queue of the messages published while the client is disconnected.
+// The queue is disabled if the directory is not set.
+type OfflineQueue struct {
+	// Directory is where the queue file is stored. The file is named after the client id.
+	Directory string `json:"directory,omitempty" yaml:"directory"`
+
+	// MaxMessages limits the number of queued messages. Defaults to 10000.
+	MaxMessages int `json:"maxMessages,omitempty" yaml:"maxMessages"`
+
+	// MaxBytes limits the total size of the queued payloads. Unlimited if not set.
+	MaxBytes int64 `json:"maxBytes,omitempty" yaml:"maxBytes"`
+
+	// MaxAge drops the messages queued for longer. Unlimited if not set.
+	MaxAge time.Duration `json:"maxAge,omitempty" yaml:"maxAge"`
+
+	// DropPolicy decides which messages are dropped when the queue is full: oldest (default) or newest.
+	DropPolicy string `json:"dropPolicy,omitempty" yaml:"dropPolicy"`
+}
+
+func (q OfflineQueue) enabled() bool {
+	return q.Directory != ""
+}
+
+func (q OfflineQueue) withDefaults() OfflineQueue {
+	if q.MaxMessages <= 0 {
+		q.MaxMessages = defaultOfflineQueueMaxMessages
+	}
+
+	if q.DropPolicy == "" {
+		q.DropPolicy = DropOldest
+	}
+
+	return q
+}
+
+// queuedMessage is a message waiting for the connection, stored as a single JSON line
+type queuedMessage struct {
+	Seq            uint64         `json:"seq"`
+	Topic          Topic          `json:"topic"`
+	Payload        []byte         `json:"payload"`
+	QoS            byte           `json:"qos"`
+	Retain         bool           `json:"retain,omitempty"`
+	ContentType    string         `json:"contentType,omitempty"`
+	MessageExpiry  time.Duration  `json:"messageExpiry,omitempty"`
+	UserProperties []UserProperty `json:"userProperties,omitempty"`
+	QueuedAt       time.Time      `json:"queuedAt"`
+}
+
+func newQueuedMessage(topic Topic, payload []byte, options *PublishOptions) queuedMessage {
+	return queuedMessage{
+		Topic:          topic,
+		Payload:        payload,
+		QoS:            options.qos,
+		Retain:         options.retain,
+		ContentType:    options.contentType,
+		MessageExpiry:  options.messageExpiry,
+		UserProperties: options.userProperties,
+		QueuedAt:       time.Now(),
+	}
+}
+
+// publishOptions restores the options of the message. The message expiry is reduced by the time spent in the queue.
+func (m queuedMessage) publishOptions(now time.Time) *PublishOptions {
+	options := &PublishOptions{
+		qos:            m.QoS,
+		retain:         m.Retain,
+		contentType:    m.ContentType,
+		userProperties: m.UserProperties,
+	}
+
+	if m.MessageExpiry > 0 {
+		options.messageExpiry = m.MessageExpiry - now.Sub(m.QueuedAt)
+	}
+
+	return options
+}
+
+// expired checks if the message is older than the maximum age or its own expiry
+func (m queuedMessage) expired(maxAge time.Duration, now time.Time) bool {
+	age := now.Sub(m.QueuedAt)
+	return maxAge > 0 && age > maxAge || m.MessageExpiry > 0 && age >= m.MessageExpiry
+}
+
+// newClientQueue creates the offline queue of the client with the metrics, nil if the queue is disabled
+func newClientQueue(config Configuration, metrics mqttMetrics) (*offlineQueue, error) {
+	if !config.OfflineQueue.enabled() {
+		return nil, nil
+	}
+
+	// The queue file is named after the client id, so the queue is resumed after a restart
+	if strings.TrimSpace(config.ClientId) == "" {
+		return nil, ErrStableClientIdRequired
+	}
+
+	return newOfflineQueue(config.OfflineQueue, config.ClientId,
+		func(messages int) {
+			metrics.RecordOfflineQueueMessages(config.ClientId, messages)
+		},
+		func(reason string, dropped int) {
+			metrics.IncrementOfflineQueueDropped(config.ClientId, reason, dropped)
+		},
+	)
+}
+
+// offlineQueue keeps the queued messages in memory and mirrors them to a JSON lines file, so they survive a restart.
+// New messages are appended to the file. Messages are only removed from the head of the queue, so the sequence
+// number of the head is stored in a separate file instead of rewriting the queue file on every removal. The queue
+// file is compacted once most of its lines are removed messages.
+type offlineQueue struct {
+	mu       sync.Mutex
+	config   OfflineQueue
+	path     string
+	headPath string
+	file     *os.File
+	messages []queuedMessage
+	bytes    int64
+	nextSeq  uint64
+	draining bool
+
+	// sending is the sequence number of the head while drain publishes it. The head is not dropped while it is sent.
+	sending   uint64
+	isSending bool
+
+	// removed is the number of lines of removed messages in the queue file
+	removed int
+	// removedBehindHead is set when a message behind the head was removed, which the head file cannot express
+	removedBehindHead bool
+
+	// onChange reports the number of queued messages and onDrop the number of dropped messages with the reason
+	onChange func(messages int)
+	onDrop   func(reason string, dropped int)
+}
+
+// newOfflineQueue opens the queue file of the client and loads the messages left from a previous run
+func newOfflineQueue(config OfflineQueue, clientId string, onChange func(int), onDrop func(string, int)) (*offlineQueue, error) {
+	config = config.withDefaults()
+	if config.DropPolicy != DropOldest && config.DropPolicy != DropNewest {
+		return nil, errors.Wrap(ErrInvalidDropPolicy, config.DropPolicy)
+	}
+
+	err := os.MkdirAll(config.Directory, 0o750)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the offline queue directory")
+	}
+
+	queue := &offlineQueue{
+		config:   config,
+		path:     filepath.Join(config.Directory, clientId+".jsonl"),
+		headPath: filepath.Join(config.Directory, clientId+".head"),
+		onChange: onChange,
+		onDrop:   onDrop,
+	}
+
+	err = queue.load()
+	if err != nil {
+		return nil, err
+	}
+
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	// Apply the limits, as they might have changed since the previous run
+	queue.dropExpired(time.Now())
+	full := 0
+	for queue.exceedsLimits(0, 0) {
+		queue.remove(0)
+		full++
+	}
+
+	if full > 0 {
+		queue.onDrop(dropReasonFull, full)
+	}
+
+	// Start with a compacted file
+	err = queue.compact()
+	if err != nil {
+		return nil, err
+	}
+
+	queue.onChange(len(queue.messages))
+	return queue, nil
+}
+
+// load reads the messages after the head from the queue file. Lines that cannot be parsed, e.g. a partially written
+// last line, are skipped.
+func (q *offlineQueue) load() error {
+	head, err := os.ReadFile(q.headPath)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to read the offline queue head")
+	}
+
+	headSeq, _ := strconv.ParseUint(strings.TrimSpace(string(head)), 10, 64)
+
+	file, err := os.Open(q.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "failed to open the offline queue file")
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxQueuedLineSize)
+	for scanner.Scan() {
+		message := queuedMessage{}
+		if json.Unmarshal(scanner.Bytes(), &message) != nil || message.Seq < headSeq {
+			continue
+		}
+
+		q.messages = append(q.messages, message)
+		q.bytes += int64(len(message.Payload))
+		q.nextSeq = max(q.nextSeq, message.Seq+1)
+	}
+
+	return errors.Wrap(scanner.Err(), "failed to read the offline queue file")
+}
+
+// enqueue appends the message to the queue. When the queue is full, either the oldest messages are dropped or the
+// message is rejected with ErrOfflineQueueFull, depending on the drop policy.
+func (q *offlineQueue) enqueue(message queuedMessage) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.dropExpired(message.QueuedAt)
+
+	// A message larger than the queue would drop all the messages and still not fit
+	if q.config.MaxBytes > 0 && int64(len(message.Payload)) > q.config.MaxBytes {
+		q.onDrop(dropReasonFull, 1)
+		return ErrOfflineQueueFull
+	}
+
+	full := 0
+	rejected := false
+	for q.exceedsLimits(1, int64(len(message.Payload))) {
+		oldest := q.oldestDroppable()
+		if q.config.DropPolicy == DropNewest || oldest < 0 {
+			rejected = true
+			break
+		}
+
+		q.remove(oldest)
+		full++
+	}
+
+	if rejected {
+		full++
+	}
+
+	if full > 0 {
+		q.onDrop(dropReasonFull, full)
+	}
+
+	err := q.sync()
+	if err != nil {
+		return err
+	}
+
+	if rejected {
+		q.onChange(len(q.messages))
+		return ErrOfflineQueueFull
+	}
+
+	message.Seq = q.nextSeq
+	q.nextSeq++
+	q.messages = append(q.messages, message)
+	q.bytes += int64(len(message.Payload))
+	q.onChange(len(q.messages))
+
+	return q.append(message)
+}
+
+// exceedsLimits checks if adding the messages with the total payload size would exceed the limits of the queue
+func (q *offlineQueue) exceedsLimits(messages int, size int64) bool {
+	if len(q.messages)+messages > q.config.MaxMessages {
+		return true
+	}
+
+	return q.config.MaxBytes > 0 && q.bytes+size > q.config.MaxBytes
+}
+
+// dropExpired removes the expired messages from the head of the queue. Messages expiring before older messages are
+// dropped once they reach the head. The message being sent is not dropped.
+func (q *offlineQueue) dropExpired(now time.Time) {
+	dropped := 0
+	for {
+		oldest := q.oldestDroppable()
+		if oldest < 0 || !q.messages[oldest].expired(q.config.MaxAge, now) {
+			break
+		}
+
+		q.remove(oldest)
+		dropped++
+	}
+
+	if dropped > 0 {
+		q.onDrop(dropReasonExpired, dropped)
+	}
+}
+
+// oldestDroppable returns the index of the oldest message, skipping the head while it is sent, or -1 if there is none
+func (q *offlineQueue) oldestDroppable() int {
+	switch {
+	case len(q.messages) == 0:
+		return -1
+	case !q.isSending || q.messages[0].Seq != q.sending:
+		return 0
+	case len(q.messages) > 1:
+		return 1
+	default:
+		return -1
+	}
+}
+
+func (q *offlineQueue) remove(i int) {
+	q.bytes -= int64(len(q.messages[i].Payload))
+	q.removed++
+
+	if i == 0 {
+		q.messages = q.messages[1:]
+		return
+	}
+
+	q.messages = append(q.messages[:i:i], q.messages[i+1:]...)
+	q.removedBehindHead = true
+}
+
+func (q *offlineQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.messages)
+}
+
+// drain publishes the queued messages in order until the queue is empty or publishing fails. Only one drain runs at
+// a time, so calling it while draining does nothing. Messages published while draining are queued and drained in order.
+func (q *offlineQueue) drain(publish func(message queuedMessage) error) error {
+	q.mu.Lock()
+	if q.draining {
+		q.mu.Unlock()
+		return nil
+	}
+	q.draining = true
+	q.mu.Unlock()
+
+	for {
+		q.mu.Lock()
+		q.dropExpired(time.Now())
+		if len(q.messages) == 0 {
+			// Stop draining while holding the lock, so a message queued after this starts a new drain
+			q.draining = false
+			q.onChange(0)
+			err := q.sync()
+			q.mu.Unlock()
+			return err
+		}
+
+		message := q.messages[0]
+		q.sending, q.isSending = message.Seq, true
+		q.mu.Unlock()
+
+		err := publish(message)
+
+		q.mu.Lock()
+		q.isSending = false
+		if err != nil {
+			q.draining = false
+			q.mu.Unlock()
+			return errors.Wrap(err, "failed to publish a queued message")
+		}
+
+		// The head is kept while it is sent, only remove it if it is still the published message
+		if len(q.messages) > 0 && q.messages[0].Seq == message.Seq {
+			q.remove(0)
+		}
+		q.onChange(len(q.messages))
+		err = q.sync()
+		if err != nil {
+			q.draining = false
+		}
+		q.mu.Unlock()
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// sync persists the removal of the messages from the head, compacting the queue file once most of it is removed
+func (q *offlineQueue) sync() error {
+	if q.removed == 0 {
+		return nil
+	}
+
+	if q.removed > len(q.messages) || q.removedBehindHead {
+		return q.compact()
+	}
+
+	head := q.nextSeq
+	if len(q.messages) > 0 {
+		head = q.messages[0].Seq
+	}
+
+	return errors.Wrap(writeFileAtomic(q.headPath, []byte(strconv.FormatUint(head, 10))), "failed to write the offline queue head")
+}
+
+// append writes the message to the end of the file
+func (q *offlineQueue) append(message queuedMessage) error {
+	line, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	if q.file == nil {
+		q.file, err = os.OpenFile(q.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o640)
+		if err != nil {
+			return errors.Wrap(err, "failed to open the offline queue file")
+		}
+	}
+
+	_, err = q.file.Write(append(line, '\n'))
+	return errors.Wrap(err, "failed to write to the offline queue file")
+}
+
+// compact replaces the queue file with the queued messages and resets the head
+func (q *offlineQueue) compact() error {
+	if q.file != nil {
+		_ = q.file.Close()
+		q.file = nil
+	}
+
+	buffer := bytes.Buffer{}
+	encoder := json.NewEncoder(&buffer)
+	for _, message := range q.messages {
+		if err := encoder.Encode(message); err != nil {
+			return errors.Wrap(err, "failed to encode a queued message")
+		}
+	}
+
+	err := writeFileAtomic(q.path, buffer.Bytes())
+	if err != nil {
+		return errors.Wrap(err, "failed to write the offline queue file")
+	}
+
+	err = os.Remove(q.headPath)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to remove the offline queue head")
+	}
+
+	q.removed = 0
+	q.removedBehindHead = false
+	return nil
+}
+
+// writeFileAtomic replaces the file by renaming a temporary file, so a crash does not leave a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+
+	err := os.WriteFile(tmpPath, data, 0o640)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// close closes the queue file. The queued messages stay in the file for the next run.
+func (q *offlineQueue) close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.file == nil {
+		return nil
+	}
+
+	err := q.file.Close()
+	q.file = nil
+	return err
+}
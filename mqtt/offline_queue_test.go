@@ -0,0 +1,292 @@
+package mqtt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xBlaz3kx/DevX/observability"
+)
+
+type queueEvents struct {
+	messages int
+	dropped  map[string]int
+}
+
+func newTestQueue(t *testing.T, config OfflineQueue) (*offlineQueue, *queueEvents) {
+	t.Helper()
+
+	events := &queueEvents{dropped: map[string]int{}}
+	queue, err := newOfflineQueue(config, "test",
+		func(messages int) { events.messages = messages },
+		func(reason string, dropped int) { events.dropped[reason] += dropped },
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = queue.close() })
+
+	return queue, events
+}
+
+func newTestMessage(topic Topic, payload string) queuedMessage {
+	return newQueuedMessage(topic, []byte(payload), newPublishOptions(1))
+}
+
+// drainTopics drains the queue and returns the topics of the published messages
+func drainTopics(t *testing.T, queue *offlineQueue) []Topic {
+	t.Helper()
+
+	topics := []Topic{}
+	require.NoError(t, queue.drain(func(message queuedMessage) error {
+		topics = append(topics, message.Topic)
+		return nil
+	}))
+
+	return topics
+}
+
+func TestOfflineQueueDrainInOrder(t *testing.T) {
+	queue, events := newTestQueue(t, OfflineQueue{Directory: t.TempDir()})
+
+	for _, topic := range []Topic{"devices/1", "devices/2", "devices/3"} {
+		require.NoError(t, queue.enqueue(newTestMessage(topic, "{}")))
+	}
+	assert.Equal(t, 3, events.messages)
+
+	assert.Equal(t, []Topic{"devices/1", "devices/2", "devices/3"}, drainTopics(t, queue))
+	assert.Equal(t, 0, queue.len())
+	assert.Equal(t, 0, events.messages)
+}
+
+func TestOfflineQueueEnqueueWhileDrainFinishes(t *testing.T) {
+	var (
+		queue          *offlineQueue
+		drainingAtZero []bool
+	)
+
+	// The change to an empty queue is reported under the lock of the queue. A message queued right after it must not
+	// see the drain as running, or it is not drained until the next publish or reconnect.
+	queue, err := newOfflineQueue(OfflineQueue{Directory: t.TempDir()}, "test", func(messages int) {
+		// The initial size is reported while opening the queue
+		if messages == 0 && queue != nil {
+			drainingAtZero = append(drainingAtZero, queue.draining)
+		}
+	}, func(string, int) {})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = queue.close() })
+
+	require.NoError(t, queue.enqueue(newTestMessage("devices/1", "{}")))
+	assert.Equal(t, []Topic{"devices/1"}, drainTopics(t, queue))
+	// Removing the last published message is reported while draining, finding the queue empty after stopping
+	assert.Equal(t, []bool{true, false}, drainingAtZero)
+
+	// The drain started after queueing the message publishes it
+	require.NoError(t, queue.enqueue(newTestMessage("devices/2", "{}")))
+	assert.Equal(t, []Topic{"devices/2"}, drainTopics(t, queue))
+}
+
+func TestOfflineQueuePersistence(t *testing.T) {
+	config := OfflineQueue{Directory: t.TempDir()}
+	queue, _ := newTestQueue(t, config)
+
+	for _, topic := range []Topic{"devices/1", "devices/2", "devices/3"} {
+		require.NoError(t, queue.enqueue(newTestMessage(topic, "{}")))
+	}
+
+	// Publishing fails after the first message, e.g. the connection is lost again
+	published := 0
+	err := queue.drain(func(message queuedMessage) error {
+		if published == 1 {
+			return errors.New("connection lost")
+		}
+
+		published++
+		return nil
+	})
+	assert.Error(t, err)
+	require.NoError(t, queue.close())
+
+	// The remaining messages are loaded after a restart
+	queue, events := newTestQueue(t, config)
+	assert.Equal(t, 2, events.messages)
+
+	require.NoError(t, queue.enqueue(newTestMessage("devices/4", "{}")))
+	assert.Equal(t, []Topic{"devices/2", "devices/3", "devices/4"}, drainTopics(t, queue))
+
+	// A partially written line is skipped
+	require.NoError(t, queue.enqueue(newTestMessage("devices/5", "{}")))
+	require.NoError(t, queue.close())
+
+	file, err := os.OpenFile(filepath.Join(config.Directory, "test.jsonl"), os.O_WRONLY|os.O_APPEND, 0o640)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"seq":10,"topic":"devi`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	queue, _ = newTestQueue(t, config)
+	assert.Equal(t, []Topic{"devices/5"}, drainTopics(t, queue))
+}
+
+func TestOfflineQueueDropPolicy(t *testing.T) {
+	t.Run("Oldest", func(t *testing.T) {
+		queue, events := newTestQueue(t, OfflineQueue{Directory: t.TempDir(), MaxMessages: 2})
+
+		for _, topic := range []Topic{"devices/1", "devices/2", "devices/3"} {
+			require.NoError(t, queue.enqueue(newTestMessage(topic, "{}")))
+		}
+
+		assert.Equal(t, 1, events.dropped[dropReasonFull])
+		assert.Equal(t, []Topic{"devices/2", "devices/3"}, drainTopics(t, queue))
+	})
+
+	t.Run("Newest", func(t *testing.T) {
+		queue, events := newTestQueue(t, OfflineQueue{Directory: t.TempDir(), MaxMessages: 2, DropPolicy: DropNewest})
+
+		require.NoError(t, queue.enqueue(newTestMessage("devices/1", "{}")))
+		require.NoError(t, queue.enqueue(newTestMessage("devices/2", "{}")))
+		assert.ErrorIs(t, queue.enqueue(newTestMessage("devices/3", "{}")), ErrOfflineQueueFull)
+
+		assert.Equal(t, 1, events.dropped[dropReasonFull])
+		assert.Equal(t, []Topic{"devices/1", "devices/2"}, drainTopics(t, queue))
+	})
+
+	t.Run("MaxBytes", func(t *testing.T) {
+		queue, _ := newTestQueue(t, OfflineQueue{Directory: t.TempDir(), MaxBytes: 10})
+
+		require.NoError(t, queue.enqueue(newTestMessage("devices/1", "12345")))
+		require.NoError(t, queue.enqueue(newTestMessage("devices/2", "12345")))
+		require.NoError(t, queue.enqueue(newTestMessage("devices/3", "123")))
+
+		// A message larger than the queue is rejected
+		assert.ErrorIs(t, queue.enqueue(newTestMessage("devices/4", "12345678901")), ErrOfflineQueueFull)
+
+		assert.Equal(t, []Topic{"devices/2", "devices/3"}, drainTopics(t, queue))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := newOfflineQueue(OfflineQueue{Directory: t.TempDir(), DropPolicy: "random"}, "test", func(int) {}, func(string, int) {})
+		assert.ErrorIs(t, err, ErrInvalidDropPolicy)
+	})
+}
+
+func TestOfflineQueueExpiry(t *testing.T) {
+	queue, events := newTestQueue(t, OfflineQueue{Directory: t.TempDir(), MaxAge: time.Minute})
+
+	old := newTestMessage("devices/1", "{}")
+	old.QueuedAt = time.Now().Add(-2 * time.Minute)
+	require.NoError(t, queue.enqueue(old))
+
+	expiring := newQueuedMessage("devices/2", []byte("{}"), newPublishOptions(1, WithMessageExpiry(time.Second)))
+	expiring.QueuedAt = time.Now().Add(-time.Second)
+	require.NoError(t, queue.enqueue(expiring))
+
+	require.NoError(t, queue.enqueue(newTestMessage("devices/3", "{}")))
+
+	assert.Equal(t, []Topic{"devices/3"}, drainTopics(t, queue))
+	assert.Equal(t, 2, events.dropped[dropReasonExpired])
+}
+
+func TestOfflineQueueKeepsMessageBeingSent(t *testing.T) {
+	// drainWhileQueueing drains the queue and queues the message while the first queued message is published
+	drainWhileQueueing := func(t *testing.T, queue *offlineQueue, message queuedMessage) []Topic {
+		topics := []Topic{}
+		require.NoError(t, queue.drain(func(published queuedMessage) error {
+			if len(topics) == 0 {
+				require.NoError(t, queue.enqueue(message))
+			}
+
+			topics = append(topics, published.Topic)
+			return nil
+		}))
+
+		return topics
+	}
+
+	t.Run("Expired", func(t *testing.T) {
+		queue, events := newTestQueue(t, OfflineQueue{Directory: t.TempDir(), MaxAge: time.Minute})
+
+		first := newTestMessage("devices/1", "{}")
+		first.QueuedAt = time.Now().Add(-30 * time.Second)
+		require.NoError(t, queue.enqueue(first))
+		require.NoError(t, queue.enqueue(newTestMessage("devices/2", "{}")))
+
+		// The message expires while it is sent
+		later := newTestMessage("devices/3", "{}")
+		later.QueuedAt = time.Now().Add(45 * time.Second)
+
+		assert.Equal(t, []Topic{"devices/1", "devices/2", "devices/3"}, drainWhileQueueing(t, queue, later))
+		assert.Zero(t, events.dropped[dropReasonExpired])
+	})
+
+	t.Run("Full", func(t *testing.T) {
+		directory := t.TempDir()
+		queue, events := newTestQueue(t, OfflineQueue{Directory: directory, MaxMessages: 2})
+
+		require.NoError(t, queue.enqueue(newTestMessage("devices/1", "{}")))
+		require.NoError(t, queue.enqueue(newTestMessage("devices/2", "{}")))
+
+		// The oldest message that is not being sent is dropped
+		assert.Equal(t, []Topic{"devices/1", "devices/3"}, drainWhileQueueing(t, queue, newTestMessage("devices/3", "{}")))
+		assert.Equal(t, 1, events.dropped[dropReasonFull])
+
+		// The dropped message is not restored
+		require.NoError(t, queue.close())
+		reopened, _ := newTestQueue(t, OfflineQueue{Directory: directory, MaxMessages: 2})
+		assert.Equal(t, 0, reopened.len())
+	})
+}
+
+func TestOfflineQueueCompaction(t *testing.T) {
+	directory := t.TempDir()
+	queue, _ := newTestQueue(t, OfflineQueue{Directory: directory})
+
+	for _, topic := range []Topic{"devices/1", "devices/2", "devices/3"} {
+		require.NoError(t, queue.enqueue(newTestMessage(topic, "{}")))
+	}
+	drainTopics(t, queue)
+
+	content, err := os.ReadFile(filepath.Join(directory, "test.jsonl"))
+	require.NoError(t, err)
+	assert.Empty(t, content)
+}
+
+func TestQueuedMessagePublishOptions(t *testing.T) {
+	message := newQueuedMessage("devices/1", []byte("{}"), newPublishOptions(0,
+		WithQoS(2),
+		WithRetain(true),
+		WithMessageExpiry(time.Minute),
+		WithContentType("application/cbor"),
+		WithUserProperty("source", "test"),
+	))
+
+	options := message.publishOptions(message.QueuedAt.Add(20 * time.Second))
+	assert.Equal(t, byte(2), options.qos)
+	assert.True(t, options.retain)
+	assert.Equal(t, 40*time.Second, options.messageExpiry)
+	assert.Equal(t, "application/cbor", options.contentType)
+	assert.Equal(t, []UserProperty{{Key: "source", Value: "test"}}, options.userProperties)
+}
+
+func TestPublishWhileDisconnectedIsQueued(t *testing.T) {
+	config := Configuration{Address: "mqtt://localhost:1883", ClientId: "test", OfflineQueue: OfflineQueue{Directory: t.TempDir()}}
+
+	v3, err := NewV3Client(config, observability.NewNoopObservability())
+	require.NoError(t, err)
+	require.NoError(t, v3.Publish(t.Context(), "devices/1", map[string]string{}))
+	assert.Equal(t, 1, v3.(*mqttV3).queue.len())
+	require.NoError(t, v3.(*mqttV3).queue.close())
+
+	config.ClientId = "test-v5"
+	v5, err := NewV5Client(config, observability.NewNoopObservability())
+	require.NoError(t, err)
+	require.NoError(t, v5.Publish(t.Context(), "devices/1", map[string]string{}))
+	assert.Equal(t, 1, v5.(*mqttV5).queue.len())
+	require.NoError(t, v5.(*mqttV5).queue.close())
+
+	config.ClientId = ""
+	_, err = NewV5Client(config, observability.NewNoopObservability())
+	assert.ErrorIs(t, err, ErrStableClientIdRequired)
+}
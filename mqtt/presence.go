@@ -0,0 +1,109 @@
+package mqtt
+
+import (
+	"strings"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidPresenceMessage is returned for a last will, birth or death message without a valid topic or QoS
+var ErrInvalidPresenceMessage = errors.New("invalid presence message")
+
+// PresenceMessage is a message published when the connection state of the client changes, e.g. to track the presence
+// of a device or service. Message expiry, content type and user properties are only used by v5.
+type PresenceMessage struct {
+	Topic          Topic          `json:"topic" yaml:"topic"`
+	Payload        string         `json:"payload,omitempty" yaml:"payload"`
+	QoS            byte           `json:"qos,omitempty" yaml:"qos"`
+	Retain         bool           `json:"retain,omitempty" yaml:"retain"`
+	MessageExpiry  time.Duration  `json:"messageExpiry,omitempty" yaml:"messageExpiry"`
+	ContentType    string         `json:"contentType,omitempty" yaml:"contentType"`
+	UserProperties []UserProperty `json:"userProperties,omitempty" yaml:"userProperties"`
+}
+
+// LastWill is the message the broker publishes when the connection of the client is lost without a clean disconnect
+type LastWill struct {
+	PresenceMessage `yaml:",inline"`
+
+	// Delay postpones the will, so it is not published if the client reconnects in the meantime. Only used by v5.
+	Delay time.Duration `json:"delay,omitempty" yaml:"delay"`
+}
+
+// validate checks that the message can be published
+func (m *PresenceMessage) validate(name string) error {
+	if m.Topic == "" || strings.ContainsAny(m.Topic.String(), "+#") {
+		return errors.Wrapf(ErrInvalidPresenceMessage, "%s topic %q", name, m.Topic)
+	}
+
+	if m.QoS > 2 {
+		return errors.Wrapf(ErrInvalidPresenceMessage, "%s QoS %d", name, m.QoS)
+	}
+
+	return nil
+}
+
+// publishOptions returns the options of the message
+func (m *PresenceMessage) publishOptions() *PublishOptions {
+	return &PublishOptions{
+		qos:            m.QoS,
+		retain:         m.Retain,
+		messageExpiry:  m.MessageExpiry,
+		contentType:    m.ContentType,
+		userProperties: m.UserProperties,
+	}
+}
+
+// pahoWill returns the will message and its properties of the v5 connect packet
+func (w *LastWill) pahoWill() (*paho.WillMessage, *paho.WillProperties) {
+	message := &paho.WillMessage{
+		Topic:   w.Topic.String(),
+		Payload: []byte(w.Payload),
+		QoS:     w.QoS,
+		Retain:  w.Retain,
+	}
+
+	properties := &paho.WillProperties{
+		ContentType: w.ContentType,
+	}
+
+	if w.Delay > 0 {
+		delay := uint32(w.Delay.Seconds())
+		properties.WillDelayInterval = &delay
+	}
+
+	if w.MessageExpiry > 0 {
+		expiry := uint32(max(w.MessageExpiry, time.Second).Seconds())
+		properties.MessageExpiry = &expiry
+	}
+
+	for _, property := range w.UserProperties {
+		properties.User.Add(property.Key, property.Value)
+	}
+
+	return message, properties
+}
+
+// validatePresence checks the last will, birth and death messages
+func (c Configuration) validatePresence() error {
+	if c.LastWill != nil {
+		if err := c.LastWill.validate("last will"); err != nil {
+			return err
+		}
+	}
+
+	if c.BirthMessage != nil {
+		if err := c.BirthMessage.validate("birth message"); err != nil {
+			return err
+		}
+	}
+
+	if c.DeathMessage != nil {
+		if err := c.DeathMessage.validate("death message"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package mqtt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xBlaz3kx/DevX/observability"
+)
+
+func TestValidatePresence(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Configuration
+		valid  bool
+	}{
+		{
+			name:   "No presence messages",
+			config: Configuration{},
+			valid:  true,
+		},
+		{
+			name: "Valid messages",
+			config: Configuration{
+				LastWill:     &LastWill{PresenceMessage: PresenceMessage{Topic: "services/example/status", Payload: "offline", QoS: 1, Retain: true}},
+				BirthMessage: &PresenceMessage{Topic: "services/example/status", Payload: "online", QoS: 1, Retain: true},
+				DeathMessage: &PresenceMessage{Topic: "services/example/status", Payload: "offline", QoS: 1, Retain: true},
+			},
+			valid: true,
+		},
+		{
+			name:   "Will without topic",
+			config: Configuration{LastWill: &LastWill{PresenceMessage: PresenceMessage{Payload: "offline"}}},
+		},
+		{
+			name:   "Birth message to a wildcard",
+			config: Configuration{BirthMessage: &PresenceMessage{Topic: "services/+/status"}},
+		},
+		{
+			name:   "Death message with invalid QoS",
+			config: Configuration{DeathMessage: &PresenceMessage{Topic: "services/example/status", QoS: 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.validatePresence()
+			if tt.valid {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, ErrInvalidPresenceMessage)
+
+			tt.config.Address = "mqtt://localhost:1883"
+			_, err = NewV3Client(tt.config, observability.NewNoopObservability())
+			assert.ErrorIs(t, err, ErrInvalidPresenceMessage)
+
+			_, err = NewV5Client(tt.config, observability.NewNoopObservability())
+			assert.ErrorIs(t, err, ErrInvalidPresenceMessage)
+		})
+	}
+}
+
+func TestLastWillV5(t *testing.T) {
+	will := LastWill{
+		PresenceMessage: PresenceMessage{
+			Topic:          "services/example/status",
+			Payload:        "offline",
+			QoS:            1,
+			Retain:         true,
+			MessageExpiry:  time.Hour,
+			ContentType:    "text/plain",
+			UserProperties: []UserProperty{{Key: "reason", Value: "connection lost"}},
+		},
+		Delay: 30 * time.Second,
+	}
+
+	message, properties := will.pahoWill()
+	assert.Equal(t, "services/example/status", message.Topic)
+	assert.Equal(t, []byte("offline"), message.Payload)
+	assert.Equal(t, byte(1), message.QoS)
+	assert.True(t, message.Retain)
+
+	require.NotNil(t, properties.WillDelayInterval)
+	assert.Equal(t, uint32(30), *properties.WillDelayInterval)
+	require.NotNil(t, properties.MessageExpiry)
+	assert.Equal(t, uint32(3600), *properties.MessageExpiry)
+	assert.Equal(t, "text/plain", properties.ContentType)
+	assert.Equal(t, "connection lost", properties.User.Get("reason"))
+
+	// The will is published immediately without a delay
+	_, properties = (&LastWill{PresenceMessage: PresenceMessage{Topic: "services/example/status"}}).pahoWill()
+	assert.Nil(t, properties.WillDelayInterval)
+	assert.Nil(t, properties.MessageExpiry)
+}
+
+func TestPresenceMessagePublish(t *testing.T) {
+	message := PresenceMessage{
+		Topic:          "services/example/status",
+		Payload:        `{"status":"online"}`,
+		QoS:            1,
+		Retain:         true,
+		ContentType:    "application/json",
+		UserProperties: []UserProperty{{Key: "version", Value: "1.2.0"}},
+	}
+
+	publish := newPahoPublish(message.Topic, []byte(message.Payload), message.publishOptions())
+	assert.Equal(t, "services/example/status", publish.Topic)
+	assert.Equal(t, []byte(`{"status":"online"}`), publish.Payload)
+	assert.Equal(t, byte(1), publish.QoS)
+	assert.True(t, publish.Retain)
+	assert.Equal(t, "application/json", publish.Properties.ContentType)
+	assert.Equal(t, "1.2.0", publish.Properties.User.Get("version"))
+}
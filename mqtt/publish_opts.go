@@ -0,0 +1,119 @@
+package mqtt
+
+import (
+	"time"
+)
+
+type PublishOpt func(*PublishOptions)
+
+// PublishOptions are the options of a published message. Message expiry, content type, user properties and topic
+// aliases are MQTT v5 features and are ignored by the v3 client.
+type PublishOptions struct {
+	qos            byte
+	retain         bool
+	messageExpiry  time.Duration
+	contentType    string
+	userProperties []UserProperty
+	topicAlias     uint16
+	codec          Codec
+	topicFilter    Topic
+}
+
+// UserProperty is a MQTT v5 user property. The same key can be used multiple times.
+type UserProperty struct {
+	Key   string
+	Value string
+}
+
+func newPublishOptions(defaultQoS byte, opts ...PublishOpt) *PublishOptions {
+	options := &PublishOptions{
+		qos: defaultQoS,
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
+
+// WithQoS sets the quality of service of the message. With QoS 1 or 2, Publish waits for the broker acknowledgement.
+// Values above 2 are capped at 2.
+func WithQoS(qos byte) PublishOpt {
+	return func(options *PublishOptions) {
+		options.qos = min(qos, 2)
+	}
+}
+
+// WithRetain makes the broker retain the message for future subscribers of the topic
+func WithRetain(retain bool) PublishOpt {
+	return func(options *PublishOptions) {
+		options.retain = retain
+	}
+}
+
+// WithMessageExpiry sets how long the broker keeps the message for subscribers that have not received it yet
+func WithMessageExpiry(expiry time.Duration) PublishOpt {
+	return func(options *PublishOptions) {
+		options.messageExpiry = expiry
+	}
+}
+
+// WithContentType sets the content type of the payload. Defaults to the content type of the codec.
+func WithContentType(contentType string) PublishOpt {
+	return func(options *PublishOptions) {
+		options.contentType = contentType
+	}
+}
+
+// WithUserProperty adds a user property to the message
+func WithUserProperty(key, value string) PublishOpt {
+	return func(options *PublishOptions) {
+		options.userProperties = append(options.userProperties, UserProperty{Key: key, Value: value})
+	}
+}
+
+// WithTopicAlias sets the topic alias of the message. The alias must not exceed the maximum announced by the broker.
+func WithTopicAlias(alias uint16) PublishOpt {
+	return func(options *PublishOptions) {
+		options.topicAlias = alias
+	}
+}
+
+// WithCodec encodes the message with the codec instead of the codec of the client
+func WithCodec(codec Codec) PublishOpt {
+	return func(options *PublishOptions) {
+		options.codec = codec
+	}
+}
+
+// WithTopicFilter labels the metrics and the span of the message with the topic filter or template the topic belongs
+// to, e.g. devices/+/status, instead of the first level of the topic
+func WithTopicFilter(filter Topic) PublishOpt {
+	return func(options *PublishOptions) {
+		options.topicFilter = filter
+	}
+}
+
+// metricsFilter returns the topic filter the metrics of the message are labelled with
+func (o *PublishOptions) metricsFilter(topic Topic) string {
+	if o.topicFilter != "" {
+		return o.topicFilter.String()
+	}
+
+	return defaultTopicFilter(topic)
+}
+
+// encode encodes the message with the codec of the options or the default codec and sets the content type of the codec,
+// unless it is set explicitly
+func (o *PublishOptions) encode(defaultCodec Codec, message interface{}) ([]byte, error) {
+	if o.codec == nil {
+		o.codec = defaultCodec
+	}
+
+	if o.contentType == "" {
+		o.contentType = o.codec.ContentType()
+	}
+
+	return o.codec.Encode(message)
+}
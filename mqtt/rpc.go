@@ -0,0 +1,279 @@
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// userPropertyError is the user property of a response, which carries the error message of a failed request
+const userPropertyError = "error"
+
+var (
+	ErrRPCNotSupported = errors.New("request/response requires MQTT v5")
+	ErrNotConnected    = errors.New("client is not connected")
+	ErrResponse        = errors.New("responded with an error")
+)
+
+// ResponseError is the error the responder replied with. It matches ErrResponse.
+type ResponseError struct {
+	Message string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrResponse, e.Message)
+}
+
+func (e *ResponseError) Is(target error) bool {
+	return target == ErrResponse
+}
+
+// RequestHandler handles a request received on a topic and returns the response.
+// The ids are the values of the topic wildcards and the request is the decoded payload. The response is encoded with
+// the codec the request was decoded with.
+type RequestHandler func(ctx context.Context, topicIds []string, request interface{}) (interface{}, error)
+
+// newResponseTopic creates the topic the client receives the responses on
+func newResponseTopic(clientId string) Topic {
+	return Topic(fmt.Sprintf("responses/%s", clientId))
+}
+
+// pendingRequests matches the responses to the requests by the correlation data
+type pendingRequests[M any] struct {
+	mu       sync.Mutex
+	requests map[string]chan M
+}
+
+func newPendingRequests[M any]() *pendingRequests[M] {
+	return &pendingRequests[M]{requests: make(map[string]chan M)}
+}
+
+// add registers a request and returns the channel its response is delivered to
+func (p *pendingRequests[M]) add(correlationId string) chan M {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	response := make(chan M, 1)
+	p.requests[correlationId] = response
+	return response
+}
+
+func (p *pendingRequests[M]) remove(correlationId string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.requests, correlationId)
+}
+
+// resolve delivers the response to the waiting request. Returns false if no request is waiting for it.
+func (p *pendingRequests[M]) resolve(correlationId string, response M) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	request, ok := p.requests[correlationId]
+	if !ok {
+		return false
+	}
+
+	// Only the first response is delivered
+	delete(p.requests, correlationId)
+	request <- response
+	return true
+}
+
+// resolveResponse delivers the response to the request with its correlation data
+func resolveResponse(pending *pendingRequests[*paho.Publish], response *paho.Publish) bool {
+	if response.Properties == nil {
+		return false
+	}
+
+	return pending.resolve(string(response.Properties.CorrelationData), response)
+}
+
+// PublishRPC publishes a request with the response topic and correlation data set and waits for the response or the
+// context to be done. The client subscribes to its response topic with the first request.
+// If the responder replied with an error, a *ResponseError is returned.
+func (c *mqttV5) PublishRPC(ctx context.Context, topic Topic, message interface{}, opts ...PublishOpt) ([]byte, error) {
+	options := newPublishOptions(0, opts...)
+	filter := options.metricsFilter(topic)
+
+	ctx, endSpan := c.producer.Span(ctx, "publish "+filter, publishSpanFields(c.clientId, topic, filter)...)
+	defer endSpan()
+
+	start := time.Now()
+	response, err := c.publishRPC(ctx, topic, message, options)
+	recordSpanError(ctx, err)
+	c.metrics.RecordMessagePublished(filter, time.Since(start), err)
+
+	return response, err
+}
+
+func (c *mqttV5) publishRPC(ctx context.Context, topic Topic, message interface{}, options *PublishOptions) ([]byte, error) {
+	cm := c.mqttClient.Load()
+	if cm == nil {
+		return nil, ErrNotConnected
+	}
+
+	logInfo := c.obs.Log().With(
+		zap.String("topic", topic.String()),
+		zap.String("responseTopic", c.responseTopic.String()),
+	)
+	logInfo.Debug("Publishing a request to topic")
+
+	err := c.subscribeResponses(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to subscribe to the response topic")
+	}
+
+	payload, err := options.encode(c.codec, message)
+	if err != nil {
+		return nil, err
+	}
+
+	injectTraceProperties(ctx, options)
+
+	correlationId := uuid.NewString()
+	response := c.pending.add(correlationId)
+	defer c.pending.remove(correlationId)
+
+	publish := newPahoPublish(topic, payload, options)
+	publish.Properties.ResponseTopic = c.responseTopic.String()
+	publish.Properties.CorrelationData = []byte(correlationId)
+
+	done := c.inflight.add()
+	_, err = cm.Publish(ctx, publish)
+	done()
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case reply := <-response:
+		if errorMessage := reply.Properties.User.Get(userPropertyError); errorMessage != "" {
+			return reply.Payload, &ResponseError{Message: errorMessage}
+		}
+
+		return reply.Payload, nil
+	case <-ctx.Done():
+		logInfo.Debug("Request timed out", zap.String("correlationId", correlationId))
+		return nil, ctx.Err()
+	}
+}
+
+// subscribeResponses subscribes to the response topic, unless already subscribed
+func (c *mqttV5) subscribeResponses(ctx context.Context) error {
+	c.responsesMu.Lock()
+	defer c.responsesMu.Unlock()
+
+	if c.responsesSubscribed {
+		return nil
+	}
+
+	err := c.subscribe(ctx, c.responseTopic, func(_ context.Context, message *paho.Publish) {
+		if !resolveResponse(c.pending, message) {
+			c.obs.Log().Debug("Received a response without a waiting request", zap.String("topic", message.Topic))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	c.responsesSubscribed = true
+	return nil
+}
+
+// SubscribeRPC subscribes to requests on the topic. The response returned by the handler is published to the
+// response topic of the request with the correlation data of the request. If the handler returns an error, the
+// response has an empty payload and the error message in the user properties.
+func (c *mqttV5) SubscribeRPC(ctx context.Context, topic Topic, handler RequestHandler, opts ...SubscribeOpt) error {
+	logInfo := c.obs.Log().With(zap.String("topic", topic.String()))
+	logInfo.Debug("Subscribing to requests on a topic")
+
+	options := newSubscribeOptions(opts...)
+
+	return c.subscribe(ctx, topic, func(ctx context.Context, message *paho.Publish) {
+		response := c.handleRequest(ctx, topic, message, options, handler)
+		if response == nil {
+			return
+		}
+
+		// The router calls the handlers from the receiving routine, which must not block on publishing
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), c.config.ConnectTimeout)
+			defer cancel()
+
+			cm := c.mqttClient.Load()
+			if cm == nil {
+				logInfo.Warn("Unable to publish the response", zap.Error(ErrNotConnected), zap.String("responseTopic", response.Topic))
+				return
+			}
+
+			_, err := cm.Publish(ctx, response)
+			if err != nil {
+				logInfo.Warn("Unable to publish the response", zap.Error(err), zap.String("responseTopic", response.Topic))
+			}
+		}()
+	})
+}
+
+// handleRequest calls the handler with the request and creates the response. Returns nil if the request does not
+// expect a response.
+func (c *mqttV5) handleRequest(ctx context.Context, topic Topic, message *paho.Publish, options *SubscribeOptions, handler RequestHandler) *paho.Publish {
+	var (
+		response interface{}
+		ids      []string
+		err      error
+	)
+
+	if strings.ContainsAny(topic.String(), "+#") {
+		ids, err = GetIdsFromTopic(c.obs.Log(), message.Topic, topic)
+	}
+
+	codec := c.messageCodec(message, options)
+
+	var request interface{}
+	if err == nil {
+		request, err = options.decode(codec, message.Payload)
+		if err != nil {
+			c.metrics.IncrementDecodeFailures(stripSharePrefix(topic))
+		}
+	}
+
+	if err == nil {
+		response, err = handler(ctx, ids, request)
+	}
+
+	recordSpanError(ctx, err)
+
+	if message.Properties == nil || message.Properties.ResponseTopic == "" {
+		return nil
+	}
+
+	publishOptions := newPublishOptions(message.QoS, WithCodec(codec))
+	if err != nil {
+		publishOptions.userProperties = append(publishOptions.userProperties, UserProperty{Key: userPropertyError, Value: err.Error()})
+		response = nil
+	}
+
+	var payload []byte
+	if response != nil {
+		payload, err = publishOptions.encode(codec, response)
+		if err != nil {
+			publishOptions.userProperties = append(publishOptions.userProperties, UserProperty{Key: userPropertyError, Value: err.Error()})
+		}
+	}
+
+	// The response continues the trace of the request
+	injectTraceProperties(ctx, publishOptions)
+
+	publish := newPahoPublish(Topic(message.Properties.ResponseTopic), payload, publishOptions)
+	publish.Properties.CorrelationData = message.Properties.CorrelationData
+	return publish
+}
@@ -0,0 +1,138 @@
+package mqtt
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newResponse(correlationId string, payload string) *paho.Publish {
+	return &paho.Publish{
+		QoS:        1,
+		Topic:      "responses/test",
+		Payload:    []byte(payload),
+		Properties: &paho.PublishProperties{CorrelationData: []byte(correlationId)},
+	}
+}
+
+func TestPendingRequests(t *testing.T) {
+	pending := newPendingRequests[*paho.Publish]()
+
+	response := pending.add("request1")
+
+	// Responses without a waiting request are not delivered
+	assert.False(t, resolveResponse(pending, newResponse("unknown", `{}`)))
+	assert.False(t, resolveResponse(pending, &paho.Publish{Topic: "responses/test"}))
+
+	assert.True(t, resolveResponse(pending, newResponse("request1", `"ok"`)))
+	assert.Equal(t, []byte(`"ok"`), (<-response).Payload)
+
+	// Only the first response is delivered
+	assert.False(t, resolveResponse(pending, newResponse("request1", `"duplicate"`)))
+
+	pending.add("request2")
+	pending.remove("request2")
+	assert.False(t, resolveResponse(pending, newResponse("request2", `{}`)))
+}
+
+func TestV5ResponseRouting(t *testing.T) {
+	client := newTestV5Client(t)
+	assert.Equal(t, Topic("responses/test"), client.responseTopic)
+
+	require.NoError(t, client.subscribeResponses(context.Background()))
+	assert.Contains(t, subscribedTopics(client), Topic("responses/test"))
+
+	response := client.pending.add("request1")
+	client.router.Route(newResponse("request1", `{"status":"accepted"}`).Packet())
+
+	select {
+	case reply := <-response:
+		assert.Equal(t, []byte(`{"status":"accepted"}`), reply.Payload)
+	default:
+		t.Fatal("response was not delivered")
+	}
+}
+
+func TestV5PublishRPCNotConnected(t *testing.T) {
+	client := newTestV5Client(t)
+
+	_, err := client.PublishRPC(context.Background(), "devices/device1/reset", map[string]string{})
+	assert.ErrorIs(t, err, ErrNotConnected)
+	assert.ErrorIs(t, client.Publish(context.Background(), "devices/device1/reset", map[string]string{}), ErrNotConnected)
+}
+
+func TestV3RPCNotSupported(t *testing.T) {
+	client := &mqttV3{}
+
+	_, err := client.PublishRPC(context.Background(), "devices/device1/reset", nil)
+	assert.ErrorIs(t, err, ErrRPCNotSupported)
+	assert.ErrorIs(t, client.SubscribeRPC(context.Background(), "devices/+/reset", nil), ErrRPCNotSupported)
+}
+
+func TestV5HandleRequest(t *testing.T) {
+	client := newTestV5Client(t)
+
+	newRequest := func(payload string, responseTopic string) *paho.Publish {
+		return &paho.Publish{
+			QoS:     1,
+			Topic:   "devices/device1/reset",
+			Payload: []byte(payload),
+			Properties: &paho.PublishProperties{
+				ResponseTopic:   responseTopic,
+				CorrelationData: []byte("request1"),
+			},
+		}
+	}
+
+	var receivedIds []string
+	handler := func(_ context.Context, ids []string, request interface{}) (interface{}, error) {
+		receivedIds = ids
+		if request == "fail" {
+			return nil, errors.New("reset failed")
+		}
+
+		return map[string]interface{}{"request": request}, nil
+	}
+
+	t.Run("Response", func(t *testing.T) {
+		response := client.handleRequest(context.Background(), "devices/+/reset", newRequest(`"hard"`, "responses/caller"), newSubscribeOptions(), handler)
+		require.NotNil(t, response)
+
+		assert.Equal(t, []string{"device1"}, receivedIds)
+		assert.Equal(t, "responses/caller", response.Topic)
+		assert.Equal(t, byte(1), response.QoS)
+		assert.Equal(t, []byte("request1"), response.Properties.CorrelationData)
+		assert.JSONEq(t, `{"request":"hard"}`, string(response.Payload))
+		assert.Empty(t, response.Properties.User.Get(userPropertyError))
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		response := client.handleRequest(context.Background(), "devices/+/reset", newRequest(`"fail"`, "responses/caller"), newSubscribeOptions(), handler)
+		require.NotNil(t, response)
+
+		assert.Empty(t, response.Payload)
+		assert.Equal(t, "reset failed", response.Properties.User.Get(userPropertyError))
+	})
+
+	t.Run("InvalidPayload", func(t *testing.T) {
+		response := client.handleRequest(context.Background(), "devices/+/reset", newRequest(`not json`, "responses/caller"), newSubscribeOptions(), handler)
+		require.NotNil(t, response)
+
+		assert.NotEmpty(t, response.Properties.User.Get(userPropertyError))
+	})
+
+	t.Run("NoResponseTopic", func(t *testing.T) {
+		assert.Nil(t, client.handleRequest(context.Background(), "devices/+/reset", newRequest(`"hard"`, ""), newSubscribeOptions(), handler))
+	})
+}
+
+func TestResponseError(t *testing.T) {
+	var err error = &ResponseError{Message: "reset failed"}
+
+	assert.ErrorIs(t, err, ErrResponse)
+	assert.Contains(t, err.Error(), "reset failed")
+}
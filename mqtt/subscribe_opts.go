@@ -0,0 +1,56 @@
+package mqtt
+
+type SubscribeOpt func(*SubscribeOptions)
+
+// SubscribeOptions are the options of a subscription
+type SubscribeOptions struct {
+	codec Codec
+
+	// newTarget creates the value the payload is decoded into, used by the typed handlers
+	newTarget func() interface{}
+}
+
+func newSubscribeOptions(opts ...SubscribeOpt) *SubscribeOptions {
+	options := &SubscribeOptions{}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
+
+// WithSubscriptionCodec decodes the payloads of the subscription with the codec instead of the codec of the client.
+// The v5 client still prefers the codec matching the content type of the message.
+func WithSubscriptionCodec(codec Codec) SubscribeOpt {
+	return func(options *SubscribeOptions) {
+		options.codec = codec
+	}
+}
+
+func withTarget(newTarget func() interface{}) SubscribeOpt {
+	return func(options *SubscribeOptions) {
+		options.newTarget = newTarget
+	}
+}
+
+// codecOrDefault returns the codec of the subscription or the default codec if it is not set
+func (o *SubscribeOptions) codecOrDefault(defaultCodec Codec) Codec {
+	if o.codec != nil {
+		return o.codec
+	}
+
+	return defaultCodec
+}
+
+// decode decodes the payload into the target of the typed handler or into an untyped value
+func (o *SubscribeOptions) decode(codec Codec, payload []byte) (interface{}, error) {
+	if o.newTarget != nil {
+		target := o.newTarget()
+		return target, codec.Decode(payload, target)
+	}
+
+	var data interface{}
+	err := codec.Decode(payload, &data)
+	return data, err
+}
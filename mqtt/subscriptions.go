@@ -0,0 +1,124 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+type SubscriptionState string
+
+const (
+	// SubscriptionPending is a subscription waiting for the connection or the broker acknowledgement
+	SubscriptionPending = SubscriptionState("pending")
+	// SubscriptionActive is a subscription acknowledged by the broker
+	SubscriptionActive = SubscriptionState("active")
+	// SubscriptionFailed is a subscription rejected by the broker or failed to be sent. It is retried after a reconnect.
+	SubscriptionFailed = SubscriptionState("failed")
+)
+
+// SubscriptionInfo is the state of a subscription, exposed for debugging
+type SubscriptionInfo struct {
+	Topic        Topic             `json:"topic"`
+	QoS          byte              `json:"qos"`
+	State        SubscriptionState `json:"state"`
+	Error        string            `json:"error,omitempty"`
+	SubscribedAt time.Time         `json:"subscribedAt,omitzero"`
+}
+
+// subscription is a registered subscription with the handler of the client library
+type subscription[H any] struct {
+	info    SubscriptionInfo
+	handler H
+}
+
+// subscriptionRegistry keeps the subscriptions of a client, so they can be restored after a reconnect
+type subscriptionRegistry[H any] struct {
+	mu            sync.Mutex
+	subscriptions map[Topic]*subscription[H]
+}
+
+func newSubscriptionRegistry[H any]() *subscriptionRegistry[H] {
+	return &subscriptionRegistry[H]{subscriptions: make(map[Topic]*subscription[H])}
+}
+
+// add registers the subscription as pending, replacing an existing subscription to the same topic
+func (r *subscriptionRegistry[H]) add(topic Topic, qos byte, handler H) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.subscriptions[topic] = &subscription[H]{
+		info:    SubscriptionInfo{Topic: topic, QoS: qos, State: SubscriptionPending},
+		handler: handler,
+	}
+}
+
+// setResult marks the subscription active or failed with the error
+func (r *subscriptionRegistry[H]) setResult(topic Topic, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub, ok := r.subscriptions[topic]
+	if !ok {
+		return
+	}
+
+	if err != nil {
+		sub.info.State = SubscriptionFailed
+		sub.info.Error = err.Error()
+		return
+	}
+
+	sub.info.State = SubscriptionActive
+	sub.info.Error = ""
+	sub.info.SubscribedAt = time.Now()
+}
+
+// setPending marks all the subscriptions pending, as the connection is down
+func (r *subscriptionRegistry[H]) setPending() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, sub := range r.subscriptions {
+		sub.info.State = SubscriptionPending
+	}
+}
+
+// all returns the registered subscriptions ordered by topic
+func (r *subscriptionRegistry[H]) all() []subscription[H] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subscriptions := make([]subscription[H], 0, len(r.subscriptions))
+	for _, sub := range r.subscriptions {
+		subscriptions = append(subscriptions, *sub)
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].info.Topic < subscriptions[j].info.Topic
+	})
+
+	return subscriptions
+}
+
+// list returns the state of the subscriptions ordered by topic
+func (r *subscriptionRegistry[H]) list() []SubscriptionInfo {
+	subscriptions := r.all()
+
+	infos := make([]SubscriptionInfo, len(subscriptions))
+	for i, sub := range subscriptions {
+		infos[i] = sub.info
+	}
+
+	return infos
+}
+
+// SubscriptionsHandler serves the state of the subscriptions of the client as JSON, for debugging
+func SubscriptionsHandler(client Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(client.Subscriptions())
+	})
+}
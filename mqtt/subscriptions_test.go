@@ -0,0 +1,102 @@
+package mqtt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xBlaz3kx/DevX/observability"
+)
+
+func subscribedTopics(client Client) []Topic {
+	topics := []Topic{}
+	for _, subscription := range client.Subscriptions() {
+		topics = append(topics, subscription.Topic)
+	}
+
+	return topics
+}
+
+func TestSubscriptionRegistry(t *testing.T) {
+	registry := newSubscriptionRegistry[string]()
+
+	registry.add("devices/+/status", 1, "status")
+	registry.add("devices/+/config", 1, "config")
+
+	subscriptions := registry.all()
+	require.Len(t, subscriptions, 2)
+	assert.Equal(t, Topic("devices/+/config"), subscriptions[0].info.Topic)
+	assert.Equal(t, "config", subscriptions[0].handler)
+	assert.Equal(t, SubscriptionPending, subscriptions[0].info.State)
+
+	registry.setResult("devices/+/status", nil)
+	registry.setResult("devices/+/config", errors.New("not authorized"))
+	registry.setResult("unknown", nil)
+
+	infos := registry.list()
+	require.Len(t, infos, 2)
+	assert.Equal(t, SubscriptionFailed, infos[0].State)
+	assert.Equal(t, "not authorized", infos[0].Error)
+	assert.Equal(t, SubscriptionActive, infos[1].State)
+	assert.False(t, infos[1].SubscribedAt.IsZero())
+
+	// Replacing a subscription resets its state
+	registry.add("devices/+/status", 2, "replaced")
+	infos = registry.list()
+	assert.Equal(t, SubscriptionPending, infos[1].State)
+	assert.Equal(t, byte(2), infos[1].QoS)
+
+	registry.setResult("devices/+/config", nil)
+	registry.setPending()
+	for _, info := range registry.list() {
+		assert.Equal(t, SubscriptionPending, info.State)
+	}
+}
+
+func TestSubackResult(t *testing.T) {
+	assert.NoError(t, subackResult(&paho.Suback{Reasons: []byte{0x01, 0x87}}, 0, errors.New("at least one requested subscription failed")))
+	assert.Error(t, subackResult(&paho.Suback{Reasons: []byte{0x01, 0x87}}, 1, nil))
+	assert.ErrorIs(t, subackResult(nil, 0, context.DeadlineExceeded), context.DeadlineExceeded)
+}
+
+func TestV3Subscriptions(t *testing.T) {
+	client, err := NewV3Client(Configuration{Address: "mqtt://localhost:1883", ClientId: "test"}, observability.NewNoopObservability())
+	require.NoError(t, err)
+
+	// The subscriptions made before connecting are kept for when the client connects
+	client.SubscribeWithId(context.Background(), "devices/+/status", func(Client, []string, uint16, interface{}, error) {})
+	require.NoError(t, client.Subscribe(context.Background(), "devices/#", func(Client, []string, uint16, interface{}, error) {}))
+	require.NoError(t, client.SubscribeShared(context.Background(), "ingestion", "devices/+/telemetry", func(Client, []string, uint16, interface{}, error) {}))
+
+	assert.Equal(t, []SubscriptionInfo{
+		{Topic: "$share/ingestion/devices/+/telemetry", QoS: 1, State: SubscriptionPending},
+		{Topic: "devices/#", QoS: 1, State: SubscriptionPending},
+		{Topic: "devices/+/status", QoS: 1, State: SubscriptionPending},
+	}, client.Subscriptions())
+}
+
+func TestPersistentSessionRequiresClientId(t *testing.T) {
+	config := Configuration{Address: "mqtt://localhost:1883", PersistentSession: true}
+
+	_, err := NewV3Client(config, observability.NewNoopObservability())
+	assert.ErrorIs(t, err, ErrStableClientIdRequired)
+
+	_, err = NewV5Client(config, observability.NewNoopObservability())
+	assert.ErrorIs(t, err, ErrStableClientIdRequired)
+}
+
+func TestSubscriptionsHandler(t *testing.T) {
+	client := newTestV5Client(t)
+	require.NoError(t, client.Subscribe(context.Background(), "devices/#", func(Client, []string, uint16, interface{}, error) {}))
+
+	recorder := httptest.NewRecorder()
+	SubscriptionsHandler(client).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/mqtt/subscriptions", nil))
+
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.JSONEq(t, `[{"topic":"devices/#","qos":1,"state":"pending"}]`, recorder.Body.String())
+}
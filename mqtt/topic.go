@@ -21,48 +21,133 @@ var (
 	ErrNotSubscribedTopic        = fmt.Errorf("not the subscribed topic")
 	ErrInvalidArgs               = fmt.Errorf("invalid number of arguments")
 	ErrInvalidIds                = fmt.Errorf("ids cannot be an empty string")
+	ErrInvalidShareGroup         = fmt.Errorf("not a valid shared subscription group")
 )
 
+// sharePrefix is the prefix of the shared subscription topics, followed by the group and the topic filter
+const sharePrefix = "$share/"
+
+// SharedTopic creates the shared subscription topic $share/{group}/{topic}. The group cannot be empty or contain the
+// characters /, + and #.
+func SharedTopic(group string, topic Topic) (Topic, error) {
+	if group == "" || strings.ContainsAny(group, "/+#") {
+		return "", ErrInvalidShareGroup
+	}
+
+	return Topic(sharePrefix + group + "/" + topic.String()), nil
+}
+
+// stripSharePrefix returns the topic filter of a shared subscription topic
+func stripSharePrefix(topic Topic) Topic {
+	if !strings.HasPrefix(topic.String(), sharePrefix) {
+		return topic
+	}
+
+	_, filter, found := strings.Cut(strings.TrimPrefix(topic.String(), sharePrefix), "/")
+	if !found {
+		return topic
+	}
+
+	return Topic(filter)
+}
+
 // GetIdsFromTopic parses the topic received from the MQTT client and returns the ids based on the original subscription topic.
 // For example:
 // actual topic = some/exampleId1/subscription/exampleId2/topic
 // subscription topic = some/+/subscription/+/topic
 // should return ["exampleId1", "exampleId2"]
-// If the topic are not the same length or don't contain the same words, it will return an error
+// A trailing # wildcard captures the remaining levels as the last id, joined with slashes. There is no id for the #
+// wildcard if the topic is the parent level of the wildcard, e.g. some/topic matches some/topic/#, while the id is
+// empty for an empty child level, e.g. some/topic/.
+// Topics starting with $ are not matched by subscription topics starting with a wildcard.
+// The prefix of a shared subscription topic ($share/{group}/) is ignored.
+// If the topics don't match or the subscription topic has no wildcards, it will return an error
 func GetIdsFromTopic(logger *otelzap.Logger, actualTopic string, subTopic Topic) ([]string, error) {
 	logger.With(
 		zap.String("actualTopic", actualTopic),
 		zap.String("originalTopic", string(subTopic)),
 	).Debug("Getting Ids from topic")
 
+	subTopic = stripSharePrefix(subTopic)
+
+	// Check if the subscription topic has at least one valid + or #
+	if !strings.ContainsAny(subTopic.String(), "+#") || !isValidFilter(subTopic.String()) {
+		return nil, ErrNotValidSubscriptionTopic
+	}
+
+	// Wildcards cannot be used in the actual topic
+	if strings.ContainsAny(actualTopic, "+#") {
+		return nil, ErrNotSubscribedTopic
+	}
+
+	return matchTopic(subTopic.String(), actualTopic)
+}
+
+// isValidFilter checks that the wildcards occupy whole levels and that # is the last level
+func isValidFilter(filter string) bool {
+	if filter == "" {
+		return false
+	}
+
+	levels := strings.Split(filter, "/")
+	for i, level := range levels {
+		if level == "+" || level == "#" && i == len(levels)-1 {
+			continue
+		}
+
+		if strings.ContainsAny(level, "+#") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchTopic matches the topic against a valid filter and returns the values of the wildcards
+func matchTopic(filter string, topic string) ([]string, error) {
 	var (
-		ids               []string
-		actualTopicValues = strings.Split(actualTopic, "/")
-		subTopicValues    = strings.Split(subTopic.String(), "/")
+		ids          = []string{}
+		filterLevels = strings.Split(filter, "/")
+		topicLevels  = strings.Split(topic, "/")
 	)
 
-	// Check if it is the same length, which would indicate the same topic
-	if len(actualTopicValues) != len(subTopicValues) {
-		return nil, ErrNotSameTopic
+	// The wildcards do not match the system topics
+	if strings.HasPrefix(topic, "$") && (filterLevels[0] == "+" || filterLevels[0] == "#") {
+		return nil, ErrNotSubscribedTopic
 	}
 
-	// Check if the subscription topic has at least one + or #
-	if !strings.ContainsAny(subTopic.String(), "+#") {
-		return nil, ErrNotValidSubscriptionTopic
-	}
+	for i, level := range filterLevels {
+		if level == "#" {
+			// The parent level matches without a child level
+			if i == len(topicLevels) {
+				return ids, nil
+			}
+
+			return append(ids, strings.Join(topicLevels[i:], "/")), nil
+		}
+
+		if i >= len(topicLevels) {
+			return nil, ErrNotSameTopic
+		}
 
-	for i, value := range subTopicValues {
-		if value != actualTopicValues[i] && value != "+" {
+		if level == "+" {
+			ids = append(ids, topicLevels[i])
+		} else if level != topicLevels[i] {
 			return nil, ErrNotSubscribedTopic
-		} else if value == "+" {
-			ids = append(ids, actualTopicValues[i])
 		}
 	}
 
+	// Check if it is the same length, which would indicate the same topic
+	if len(topicLevels) != len(filterLevels) {
+		return nil, ErrNotSameTopic
+	}
+
 	return ids, nil
 }
 
 // CreateTopicWithIds replaces all the + sign in a topic used for subscription with ids. Works only if the number of pluses is matches the number of ids.
+//
+// Deprecated: use TopicTemplate.Fill, which names the parameters and validates the reserved characters.
 func CreateTopicWithIds(logger *otelzap.Logger, topicTemplate Topic, ids ...string) (string, error) {
 	logger.With(
 		zap.String("topic", string(topicTemplate)),
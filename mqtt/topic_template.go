@@ -0,0 +1,336 @@
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrInvalidTemplate   = fmt.Errorf("not a valid topic template")
+	ErrInvalidTopicParam = fmt.Errorf("not a valid topic parameter")
+	ErrMissingTopicParam = fmt.Errorf("missing topic parameter")
+	ErrUnsupportedTarget = fmt.Errorf("unsupported binding target")
+)
+
+// topicTag is the struct tag naming the topic parameter of a field
+const topicTag = "topic"
+
+// TopicParams are the values of the named parameters of a topic template
+type TopicParams map[string]string
+
+// TopicTemplate is a topic with named parameters, such as devices/{deviceId}/sensors/{sensorId}/data.
+// The parameters are subscribed to as + wildcards. The last level can be a # wildcard, which is not bound.
+type TopicTemplate struct {
+	template string
+	levels   []templateLevel
+}
+
+// templateLevel is either a literal level or a named parameter
+type templateLevel struct {
+	literal string
+	param   string
+}
+
+// ParseTopicTemplate parses and validates a topic template
+func ParseTopicTemplate(template string) (TopicTemplate, error) {
+	if template == "" {
+		return TopicTemplate{}, errors.Wrap(ErrInvalidTemplate, "empty template")
+	}
+
+	var (
+		levels = strings.Split(template, "/")
+		parsed = make([]templateLevel, 0, len(levels))
+		params = make(map[string]bool)
+	)
+
+	for i, level := range levels {
+		switch {
+		case strings.HasPrefix(level, "{") && strings.HasSuffix(level, "}"):
+			name := level[1 : len(level)-1]
+			if !isParamName(name) {
+				return TopicTemplate{}, errors.Wrapf(ErrInvalidTemplate, "invalid parameter name %q", name)
+			}
+
+			if params[name] {
+				return TopicTemplate{}, errors.Wrapf(ErrInvalidTemplate, "duplicate parameter %q", name)
+			}
+
+			params[name] = true
+			parsed = append(parsed, templateLevel{param: name})
+		case level == "#" && i == len(levels)-1:
+			parsed = append(parsed, templateLevel{literal: level})
+		case strings.ContainsAny(level, "+#{}\x00"):
+			return TopicTemplate{}, errors.Wrapf(ErrInvalidTemplate, "invalid level %q", level)
+		default:
+			parsed = append(parsed, templateLevel{literal: level})
+		}
+	}
+
+	return TopicTemplate{template: template, levels: parsed}, nil
+}
+
+// MustParseTopicTemplate parses the topic template and panics if it is not valid. Intended for package level templates.
+func MustParseTopicTemplate(template string) TopicTemplate {
+	parsed, err := ParseTopicTemplate(template)
+	if err != nil {
+		panic(err)
+	}
+
+	return parsed
+}
+
+func isParamName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, r := range name {
+		if !(r == '_' || r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (t TopicTemplate) String() string {
+	return t.template
+}
+
+// Params returns the names of the parameters in the order of the levels
+func (t TopicTemplate) Params() []string {
+	params := []string{}
+	for _, level := range t.levels {
+		if level.param != "" {
+			params = append(params, level.param)
+		}
+	}
+
+	return params
+}
+
+// Filter returns the subscription filter, with the parameters replaced by + wildcards
+func (t TopicTemplate) Filter() Topic {
+	levels := make([]string, len(t.levels))
+	for i, level := range t.levels {
+		if level.param != "" {
+			levels[i] = "+"
+		} else {
+			levels[i] = level.literal
+		}
+	}
+
+	return Topic(strings.Join(levels, "/"))
+}
+
+// Match returns the parameters of the topic, or an error if the topic does not match the template
+func (t TopicTemplate) Match(topic string) (TopicParams, error) {
+	if strings.ContainsAny(topic, "+#") {
+		return nil, ErrNotSubscribedTopic
+	}
+
+	ids, err := matchTopic(t.Filter().String(), topic)
+	if err != nil {
+		return nil, err
+	}
+
+	// The parameters are the + wildcards, in the order of the levels. The tail of the # wildcard is not bound.
+	params := make(TopicParams)
+	for i, name := range t.Params() {
+		params[name] = ids[i]
+	}
+
+	return params, nil
+}
+
+// Bind matches the topic and binds the parameters to the target, see TopicParams.Bind
+func (t TopicTemplate) Bind(topic string, target interface{}) error {
+	params, err := t.Match(topic)
+	if err != nil {
+		return err
+	}
+
+	return params.Bind(target)
+}
+
+// Fill creates the topic to publish to, with the parameters replaced by the values. The values can be TopicParams,
+// a map[string]string or a struct with the fields named like in TopicParams.Bind. Every parameter must have a value, which
+// cannot be empty or contain the reserved characters /, +, # and null. Templates ending with # can only be subscribed to.
+func (t TopicTemplate) Fill(values interface{}) (Topic, error) {
+	params, err := toTopicParams(values)
+	if err != nil {
+		return "", err
+	}
+
+	levels := make([]string, len(t.levels))
+	for i, level := range t.levels {
+		if level.literal == "#" {
+			return "", errors.Wrap(ErrInvalidTemplate, "cannot publish to a template with a # wildcard")
+		}
+
+		if level.param == "" {
+			levels[i] = level.literal
+			continue
+		}
+
+		value, ok := params.get(level.param)
+		if !ok {
+			return "", errors.Wrap(ErrMissingTopicParam, level.param)
+		}
+
+		if value == "" || strings.ContainsAny(value, "/+#\x00") {
+			return "", errors.Wrapf(ErrInvalidTopicParam, "%s=%q", level.param, value)
+		}
+
+		levels[i] = value
+	}
+
+	return Topic(strings.Join(levels, "/")), nil
+}
+
+// Bind sets the parameters to the target, which is a pointer to a map[string]string or a struct. The struct fields are
+// matched by the topic tag or by the field name, ignoring the case. String, integer and unsigned integer fields are supported.
+func (p TopicParams) Bind(target interface{}) error {
+	if params, ok := target.(*map[string]string); ok {
+		if *params == nil {
+			*params = make(map[string]string, len(p))
+		}
+
+		for name, value := range p {
+			(*params)[name] = value
+		}
+
+		return nil
+	}
+
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return errors.Wrapf(ErrUnsupportedTarget, "%T", target)
+	}
+
+	structValue := value.Elem()
+	for i := 0; i < structValue.NumField(); i++ {
+		field := structValue.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		param, ok := p.lookup(field)
+		if !ok {
+			continue
+		}
+
+		if err := setField(structValue.Field(i), param); err != nil {
+			return errors.Wrapf(err, "field %s", field.Name)
+		}
+	}
+
+	return nil
+}
+
+// lookup returns the parameter of the struct field
+func (p TopicParams) lookup(field reflect.StructField) (string, bool) {
+	if name := field.Tag.Get(topicTag); name != "" {
+		value, ok := p[name]
+		return value, ok
+	}
+
+	return p.get(field.Name)
+}
+
+// get returns the parameter with the name, ignoring the case if there is no exact match
+func (p TopicParams) get(name string) (string, bool) {
+	if value, ok := p[name]; ok {
+		return value, true
+	}
+
+	for param, value := range p {
+		if strings.EqualFold(param, name) {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+func setField(field reflect.Value, param string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(param)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value, err := strconv.ParseInt(param, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value, err := strconv.ParseUint(param, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(value)
+	default:
+		return errors.Wrapf(ErrUnsupportedTarget, "%s", field.Type())
+	}
+
+	return nil
+}
+
+// toTopicParams converts the values of Fill to the parameters
+func toTopicParams(values interface{}) (TopicParams, error) {
+	switch values := values.(type) {
+	case TopicParams:
+		return values, nil
+	case map[string]string:
+		return values, nil
+	case nil:
+		return TopicParams{}, nil
+	}
+
+	value := reflect.ValueOf(values)
+	if value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		return nil, errors.Wrapf(ErrUnsupportedTarget, "%T", values)
+	}
+
+	params := make(TopicParams)
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Tag.Get(topicTag)
+		if name == "" {
+			name = field.Name
+		}
+
+		params[name] = fmt.Sprint(value.Field(i).Interface())
+	}
+
+	return params, nil
+}
+
+// TemplateHandler receives the parameters of the topic of the message
+type TemplateHandler func(client Client, params TopicParams, payloadId uint16, payload interface{}, err error)
+
+// SubscribeTemplate subscribes to the filter of the template and passes the parameters of the received topics to the handler
+func SubscribeTemplate(ctx context.Context, client Client, template TopicTemplate, handler TemplateHandler, opts ...SubscribeOpt) error {
+	return client.Subscribe(ctx, template.Filter(), func(client Client, topicLevels []string, payloadId uint16, payload interface{}, err error) {
+		// Subscribe passes the levels of the received topic
+		params, matchErr := template.Match(strings.Join(topicLevels, "/"))
+		if err == nil {
+			err = matchErr
+		}
+
+		handler(client, params, payloadId, payload, err)
+	}, opts...)
+}
@@ -0,0 +1,120 @@
+package mqtt
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sensorTopic struct {
+	DeviceId string
+	Sensor   int `topic:"sensorId"`
+}
+
+func TestParseTopicTemplate(t *testing.T) {
+	template, err := ParseTopicTemplate("devices/{deviceId}/sensors/{sensorId}/data")
+	require.NoError(t, err)
+	assert.Equal(t, Topic("devices/+/sensors/+/data"), template.Filter())
+	assert.Equal(t, []string{"deviceId", "sensorId"}, template.Params())
+	assert.Equal(t, "devices/{deviceId}/sensors/{sensorId}/data", template.String())
+
+	template, err = ParseTopicTemplate("devices/{deviceId}/#")
+	require.NoError(t, err)
+	assert.Equal(t, Topic("devices/+/#"), template.Filter())
+
+	invalid := []string{
+		"",
+		"devices/{}/data",
+		"devices/{device id}/data",
+		"devices/{deviceId}/{deviceId}",
+		"devices/+/data",
+		"devices/#/data",
+		"devices/{deviceId/data",
+		"devices/prefix{deviceId}/data",
+	}
+	for _, template := range invalid {
+		_, err = ParseTopicTemplate(template)
+		assert.ErrorIs(t, err, ErrInvalidTemplate, template)
+	}
+
+	assert.Panics(t, func() { MustParseTopicTemplate("devices/+") })
+}
+
+func TestTopicTemplateMatch(t *testing.T) {
+	template := MustParseTopicTemplate("devices/{deviceId}/sensors/{sensorId}/data")
+
+	params, err := template.Match("devices/device1/sensors/42/data")
+	require.NoError(t, err)
+	assert.Equal(t, TopicParams{"deviceId": "device1", "sensorId": "42"}, params)
+
+	for _, topic := range []string{"devices/device1/sensors/42", "devices/device1/sensors/42/data/raw", "devices/device1/actuators/42/data"} {
+		_, err = template.Match(topic)
+		assert.Error(t, err, topic)
+	}
+
+	params, err = MustParseTopicTemplate("devices/{deviceId}/#").Match("devices/device1/sensors/42")
+	require.NoError(t, err)
+	assert.Equal(t, TopicParams{"deviceId": "device1"}, params)
+}
+
+func TestTopicTemplateBind(t *testing.T) {
+	template := MustParseTopicTemplate("devices/{deviceId}/sensors/{sensorId}/data")
+
+	target := sensorTopic{}
+	require.NoError(t, template.Bind("devices/device1/sensors/42/data", &target))
+	assert.Equal(t, sensorTopic{DeviceId: "device1", Sensor: 42}, target)
+
+	var params map[string]string
+	require.NoError(t, template.Bind("devices/device1/sensors/42/data", &params))
+	assert.Equal(t, map[string]string{"deviceId": "device1", "sensorId": "42"}, params)
+
+	assert.Error(t, template.Bind("devices/device1/sensors/temperature/data", &target))
+	assert.ErrorIs(t, template.Bind("devices/device1/sensors/42/data", target), ErrUnsupportedTarget)
+}
+
+func TestTopicTemplateFill(t *testing.T) {
+	template := MustParseTopicTemplate("devices/{deviceId}/sensors/{sensorId}/data")
+
+	topic, err := template.Fill(map[string]string{"deviceId": "device1", "sensorId": "42"})
+	require.NoError(t, err)
+	assert.Equal(t, Topic("devices/device1/sensors/42/data"), topic)
+
+	topic, err = template.Fill(sensorTopic{DeviceId: "device2", Sensor: 7})
+	require.NoError(t, err)
+	assert.Equal(t, Topic("devices/device2/sensors/7/data"), topic)
+
+	_, err = template.Fill(TopicParams{"deviceId": "device1"})
+	assert.ErrorIs(t, err, ErrMissingTopicParam)
+
+	for _, value := range []string{"", "device/1", "device+", "device#", "device\x00"} {
+		_, err = template.Fill(TopicParams{"deviceId": value, "sensorId": "42"})
+		assert.ErrorIs(t, err, ErrInvalidTopicParam, value)
+	}
+
+	_, err = template.Fill(42)
+	assert.ErrorIs(t, err, ErrUnsupportedTarget)
+
+	// Templates with a # wildcard are only used for subscribing
+	_, err = MustParseTopicTemplate("devices/{deviceId}/#").Fill(TopicParams{"deviceId": "42"})
+	assert.ErrorIs(t, err, ErrInvalidTemplate)
+}
+
+func TestSubscribeTemplate(t *testing.T) {
+	client := newTestV5Client(t)
+	template := MustParseTopicTemplate("devices/{deviceId}/sensors/{sensorId}/data")
+
+	var received []TopicParams
+	err := SubscribeTemplate(context.Background(), client, template, func(_ Client, params TopicParams, _ uint16, _ interface{}, err error) {
+		require.NoError(t, err)
+		received = append(received, params)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, subscribedTopics(client), Topic("devices/+/sensors/+/data"))
+
+	routeMessage(client, "devices/device1/sensors/42/data", `{"temperature":21.5}`)
+
+	require.Len(t, received, 1)
+	assert.Equal(t, TopicParams{"deviceId": "device1", "sensorId": "42"}, received[0])
+}
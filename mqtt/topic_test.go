@@ -2,7 +2,10 @@
 package mqtt
 
 import (
+	"regexp"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/suite"
 	"github.com/xBlaz3kx/DevX/observability"
@@ -40,6 +43,69 @@ func (suite *mqttTestSuite) TestGetIdsFromTopic() {
 	suite.Require().Equal([]string{"examplePlugin", "example2"}, ids)
 }
 
+func (suite *mqttTestSuite) TestGetIdsFromTopicMultiLevelWildcard() {
+	tests := []struct {
+		actualTopic string
+		subTopic    Topic
+		expectedIds []string
+	}{
+		{"cmd/examplePlugin/execute/now", "cmd/#", []string{"examplePlugin/execute/now"}},
+		{"cmd/examplePlugin/execute/now", "cmd/+/#", []string{"examplePlugin", "execute/now"}},
+		{"cmd/examplePlugin", "cmd/+/#", []string{"examplePlugin"}},
+		{"cmd/examplePlugin/", "cmd/+/#", []string{"examplePlugin", ""}},
+		{"cmd", "cmd/#", []string{}},
+		{"cmd/", "cmd/#", []string{""}},
+		{"cmd/examplePlugin", "#", []string{"cmd/examplePlugin"}},
+		{"/cmd", "+/cmd", []string{""}},
+		{"$SYS/broker/uptime", "$SYS/#", []string{"broker/uptime"}},
+		{"$SYS/broker/uptime", "$SYS/+/uptime", []string{"broker"}},
+	}
+
+	for _, test := range tests {
+		ids, err := GetIdsFromTopic(suite.obs.Log(), test.actualTopic, test.subTopic)
+		suite.Require().NoError(err, test.subTopic)
+		suite.Require().Equal(test.expectedIds, ids, test.subTopic)
+	}
+
+	// Wildcards at the first level do not match the system topics
+	_, err := GetIdsFromTopic(suite.obs.Log(), "$SYS/broker/uptime", "#")
+	suite.Require().ErrorIs(err, ErrNotSubscribedTopic)
+
+	_, err = GetIdsFromTopic(suite.obs.Log(), "$SYS/broker/uptime", "+/broker/uptime")
+	suite.Require().ErrorIs(err, ErrNotSubscribedTopic)
+
+	_, err = GetIdsFromTopic(suite.obs.Log(), "other/examplePlugin", "cmd/#")
+	suite.Require().ErrorIs(err, ErrNotSubscribedTopic)
+
+	// The wildcards must occupy whole levels and # must be the last level
+	for _, subTopic := range []Topic{"cmd/#/execute", "cmd/plugin#", "cmd/plugin+/execute", "cmd/##"} {
+		_, err = GetIdsFromTopic(suite.obs.Log(), "cmd/examplePlugin/execute", subTopic)
+		suite.Require().ErrorIs(err, ErrNotValidSubscriptionTopic, subTopic)
+	}
+
+	// Wildcards are not valid in the actual topic
+	_, err = GetIdsFromTopic(suite.obs.Log(), "cmd/+", "cmd/#")
+	suite.Require().ErrorIs(err, ErrNotSubscribedTopic)
+}
+
+func (suite *mqttTestSuite) TestSharedTopic() {
+	topic, err := SharedTopic("ingestion", "devices/+/status")
+	suite.Require().NoError(err)
+	suite.Require().Equal(Topic("$share/ingestion/devices/+/status"), topic)
+	suite.Require().Equal(Topic("devices/+/status"), stripSharePrefix(topic))
+	suite.Require().Equal(Topic("devices/+/status"), stripSharePrefix("devices/+/status"))
+
+	for _, group := range []string{"", "ingestion/1", "ingestion+", "#"} {
+		_, err = SharedTopic(group, "devices/+/status")
+		suite.Require().ErrorIs(err, ErrInvalidShareGroup, group)
+	}
+
+	// The share prefix is ignored when matching
+	ids, err := GetIdsFromTopic(suite.obs.Log(), "devices/device1/status", topic)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]string{"device1"}, ids)
+}
+
 func (suite *mqttTestSuite) TestCreateTopicWithIds() {
 	ids, err := CreateTopicWithIds(suite.obs.Log(), "cmd/+/execute", "exampleId")
 	suite.Require().NoError(err)
@@ -59,3 +125,96 @@ func (suite *mqttTestSuite) TestCreateTopicWithIds() {
 func TestGetIdsFromTopic(t *testing.T) {
 	suite.Run(t, new(mqttTestSuite))
 }
+
+// referenceMatch matches the topic with a regular expression built from the filter, following the MQTT specification
+func referenceMatch(filter, topic string) bool {
+	if strings.HasPrefix(topic, "$") && (strings.HasPrefix(filter, "+") || strings.HasPrefix(filter, "#")) {
+		return false
+	}
+
+	var (
+		levels  = strings.Split(filter, "/")
+		pattern = ""
+	)
+
+	for i, level := range levels {
+		separator := "/"
+		if i == 0 {
+			separator = ""
+		}
+
+		switch {
+		case level == "#" && i == 0:
+			pattern = ".*"
+		case level == "#":
+			// Matches the parent level and any number of child levels
+			pattern += "(/.*)?"
+		case level == "+":
+			pattern += separator + "[^/]*"
+		default:
+			pattern += separator + regexp.QuoteMeta(level)
+		}
+	}
+
+	return regexp.MustCompile("(?s)^" + pattern + "$").MatchString(topic)
+}
+
+func FuzzGetIdsFromTopic(f *testing.F) {
+	seeds := []struct {
+		topic  string
+		filter string
+	}{
+		{"cmd/examplePlugin/execute", "cmd/+/execute"},
+		{"cmd/examplePlugin/execute/now", "cmd/#"},
+		{"cmd", "cmd/#"},
+		{"cmd/", "cmd/#"},
+		{"$SYS/broker/uptime", "#"},
+		{"$SYS/broker/uptime", "$SYS/+/uptime"},
+		{"/cmd//execute", "+/cmd/+/#"},
+		{"cmd/examplePlugin", "cmd/+/+"},
+	}
+	for _, seed := range seeds {
+		f.Add(seed.topic, seed.filter)
+	}
+
+	logger := observability.NewNoopObservability().Log()
+
+	f.Fuzz(func(t *testing.T, topic string, filter string) {
+		// Topics are UTF-8 strings, which the reference matcher requires
+		if !utf8.ValidString(topic) || !utf8.ValidString(filter) {
+			t.Skip()
+		}
+
+		if !strings.ContainsAny(filter, "+#") || !isValidFilter(filter) || strings.ContainsAny(topic, "+#") {
+			t.Skip()
+		}
+
+		ids, err := GetIdsFromTopic(logger, topic, Topic(filter))
+		if referenceMatch(filter, topic) != (err == nil) {
+			t.Fatalf("filter %q and topic %q: reference match %v, got error %v", filter, topic, referenceMatch(filter, topic), err)
+		}
+
+		if err != nil {
+			return
+		}
+
+		// Replacing the wildcards with the ids restores the topic. A # wildcard without an id matched the parent level.
+		levels := strings.Split(filter, "/")
+		for i, j := 0, 0; i < len(levels); i++ {
+			if levels[i] == "#" && j == len(ids) {
+				levels = levels[:i]
+				break
+			}
+
+			if levels[i] == "+" || levels[i] == "#" {
+				levels[i] = ids[j]
+				j++
+			}
+		}
+
+		restored := strings.Join(levels, "/")
+		if restored != topic {
+			t.Fatalf("filter %q and ids %q restore %q instead of %q", filter, ids, restored, topic)
+		}
+	})
+}
@@ -0,0 +1,188 @@
+package mqtt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/eclipse/paho.golang/paho"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Messaging attributes of the spans, following the OpenTelemetry semantic conventions
+const (
+	attrMessagingSystem              = "messaging.system"
+	attrMessagingOperationType       = "messaging.operation.type"
+	attrMessagingDestinationName     = "messaging.destination.name"
+	attrMessagingDestinationTemplate = "messaging.destination.template"
+	attrMessagingMessageId           = "messaging.message.id"
+	attrMessagingBodySize            = "messaging.message.body.size"
+	attrMessagingClientId            = "messaging.client.id"
+
+	messagingSystem = "mqtt"
+)
+
+// publishSpanFields are the attributes of the producer span of a published message
+func publishSpanFields(clientId string, topic Topic, filter string) []zap.Field {
+	return []zap.Field{
+		zap.String(attrMessagingSystem, messagingSystem),
+		zap.String(attrMessagingOperationType, "send"),
+		zap.String(attrMessagingDestinationName, topic.String()),
+		zap.String(attrMessagingDestinationTemplate, filter),
+		zap.String(attrMessagingClientId, clientId),
+	}
+}
+
+// processSpanFields are the attributes of the consumer span of a received message
+func processSpanFields(clientId string, topic string, filter Topic, messageId uint16, payloadSize int) []zap.Field {
+	return []zap.Field{
+		zap.String(attrMessagingSystem, messagingSystem),
+		zap.String(attrMessagingOperationType, "process"),
+		zap.String(attrMessagingDestinationName, topic),
+		zap.String(attrMessagingDestinationTemplate, filter.String()),
+		zap.String(attrMessagingClientId, clientId),
+		zap.Uint16(attrMessagingMessageId, messageId),
+		zap.Int(attrMessagingBodySize, payloadSize),
+	}
+}
+
+// recordSpanError marks the span of the context as failed
+func recordSpanError(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// defaultTopicFilter is the metrics label of a published topic without a topic filter. Only the first level is kept,
+// as the other levels usually contain ids.
+func defaultTopicFilter(topic Topic) string {
+	first, _, found := strings.Cut(topic.String(), "/")
+	if !found {
+		return topic.String()
+	}
+
+	return first + "/#"
+}
+
+// userPropertiesCarrier injects the trace context into the user properties of a published v5 message
+type userPropertiesCarrier struct {
+	properties *[]UserProperty
+}
+
+func (c userPropertiesCarrier) Get(key string) string {
+	for _, property := range *c.properties {
+		if property.Key == key {
+			return property.Value
+		}
+	}
+
+	return ""
+}
+
+// Set replaces the existing values of the key
+func (c userPropertiesCarrier) Set(key string, value string) {
+	properties := (*c.properties)[:0:0]
+	for _, property := range *c.properties {
+		if property.Key != key {
+			properties = append(properties, property)
+		}
+	}
+
+	*c.properties = append(properties, UserProperty{Key: key, Value: value})
+}
+
+func (c userPropertiesCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.properties))
+	for _, property := range *c.properties {
+		keys = append(keys, property.Key)
+	}
+
+	return keys
+}
+
+// pahoPropertiesCarrier extracts the trace context from the user properties of a received v5 message
+type pahoPropertiesCarrier paho.UserProperties
+
+func (c pahoPropertiesCarrier) Get(key string) string {
+	return paho.UserProperties(c).Get(key)
+}
+
+func (c pahoPropertiesCarrier) Set(key string, value string) {
+	// The received properties are read only
+}
+
+func (c pahoPropertiesCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for _, property := range c {
+		keys = append(keys, property.Key)
+	}
+
+	return keys
+}
+
+// injectTraceProperties adds the trace context of the context to the user properties of the message
+func injectTraceProperties(ctx context.Context, options *PublishOptions) {
+	otel.GetTextMapPropagator().Inject(ctx, userPropertiesCarrier{properties: &options.userProperties})
+}
+
+// extractTraceProperties returns the context with the trace context of the received message
+func extractTraceProperties(ctx context.Context, message *paho.Publish) context.Context {
+	if message.Properties == nil {
+		return ctx
+	}
+
+	return otel.GetTextMapPropagator().Extract(ctx, pahoPropertiesCarrier(message.Properties.User))
+}
+
+// traceEnvelope wraps the payload of a v3 message with the trace context, as MQTT v3 has no user properties.
+// JSON payloads are embedded as they are, other payloads are base64 encoded.
+type traceEnvelope struct {
+	TraceContext map[string]string `json:"traceContext"`
+	Payload      json.RawMessage   `json:"payload,omitempty"`
+	Data         []byte            `json:"data,omitempty"`
+}
+
+// wrapTraceEnvelope wraps the encoded payload with the trace context of the context
+func wrapTraceEnvelope(ctx context.Context, payload []byte, codec Codec) ([]byte, error) {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+
+	envelope := traceEnvelope{TraceContext: carrier}
+	if codec.ContentType() == JSONCodec.ContentType() {
+		envelope.Payload = payload
+	} else {
+		envelope.Data = payload
+	}
+
+	return json.Marshal(envelope)
+}
+
+// unwrapTraceEnvelope returns the context with the trace context of the envelope and the wrapped payload.
+// Payloads without an envelope are returned unchanged, so publishers without tracing are still supported.
+func unwrapTraceEnvelope(ctx context.Context, payload []byte) (context.Context, []byte) {
+	if !bytes.HasPrefix(bytes.TrimSpace(payload), []byte("{")) {
+		return ctx, payload
+	}
+
+	envelope := traceEnvelope{}
+	err := json.Unmarshal(payload, &envelope)
+	if err != nil || envelope.TraceContext == nil {
+		return ctx, payload
+	}
+
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(envelope.TraceContext))
+	if envelope.Payload != nil {
+		return ctx, envelope.Payload
+	}
+
+	return ctx, envelope.Data
+}
@@ -0,0 +1,143 @@
+package mqtt
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// withTraceContext sets the W3C trace context propagator for the test and returns a context with a sampled span
+func withTraceContext(t *testing.T) context.Context {
+	t.Helper()
+
+	propagator := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(propagator) })
+
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01, 0x02, 0x03},
+		SpanID:     trace.SpanID{0x04, 0x05},
+		TraceFlags: trace.FlagsSampled,
+	})
+	return trace.ContextWithSpanContext(context.Background(), spanContext)
+}
+
+func TestTraceUserProperties(t *testing.T) {
+	ctx := withTraceContext(t)
+
+	options := newPublishOptions(0, WithUserProperty("source", "test"), WithUserProperty("traceparent", "stale"))
+	injectTraceProperties(ctx, options)
+
+	// The trace context replaces the existing value
+	publish := newPahoPublish("devices/device1", nil, options)
+	assert.Equal(t, "test", publish.Properties.User.Get("source"))
+	assert.Len(t, publish.Properties.User.GetAll("traceparent"), 1)
+
+	received := extractTraceProperties(context.Background(), publish)
+	spanContext := trace.SpanContextFromContext(received)
+	assert.True(t, spanContext.IsRemote())
+	assert.Equal(t, trace.SpanContextFromContext(ctx).TraceID(), spanContext.TraceID())
+
+	// Messages without properties have no trace context
+	received = extractTraceProperties(context.Background(), &paho.Publish{})
+	assert.False(t, trace.SpanContextFromContext(received).IsValid())
+}
+
+func TestTraceEnvelope(t *testing.T) {
+	ctx := withTraceContext(t)
+
+	t.Run("JSON payloads are embedded", func(t *testing.T) {
+		wrapped, err := wrapTraceEnvelope(ctx, []byte(`{"status":"online"}`), JSONCodec)
+		require.NoError(t, err)
+		assert.Contains(t, string(wrapped), `"payload":{"status":"online"}`)
+
+		received, payload := unwrapTraceEnvelope(context.Background(), wrapped)
+		assert.JSONEq(t, `{"status":"online"}`, string(payload))
+		assert.Equal(t, trace.SpanContextFromContext(ctx).TraceID(), trace.SpanContextFromContext(received).TraceID())
+	})
+
+	t.Run("Other payloads are kept as they are", func(t *testing.T) {
+		wrapped, err := wrapTraceEnvelope(ctx, []byte{0x00, 0xff, '{'}, RawCodec)
+		require.NoError(t, err)
+
+		received, payload := unwrapTraceEnvelope(context.Background(), wrapped)
+		assert.Equal(t, []byte{0x00, 0xff, '{'}, payload)
+		assert.True(t, trace.SpanContextFromContext(received).IsValid())
+	})
+
+	t.Run("Payloads without an envelope are unchanged", func(t *testing.T) {
+		for _, payload := range []string{`{"status":"online"}`, `"online"`, `{invalid`, ``} {
+			received, unwrapped := unwrapTraceEnvelope(context.Background(), []byte(payload))
+			assert.Equal(t, payload, string(unwrapped))
+			assert.False(t, trace.SpanContextFromContext(received).IsValid())
+		}
+	})
+}
+
+func TestPublishTopicFilter(t *testing.T) {
+	assert.Equal(t, "devices/#", newPublishOptions(0).metricsFilter("devices/device1/status"))
+	assert.Equal(t, "status", newPublishOptions(0).metricsFilter("status"))
+	assert.Equal(t, "devices/+/status", newPublishOptions(0, WithTopicFilter("devices/+/status")).metricsFilter("devices/device1/status"))
+}
+
+func TestV5ReceivedMessageTelemetry(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	t.Cleanup(func() { otel.SetMeterProvider(provider) })
+
+	ctx := withTraceContext(t)
+	client := newTestV5Client(t)
+
+	var handlerCtx context.Context
+	require.NoError(t, client.subscribe(context.Background(), "$share/ingestion/devices/+/status", func(ctx context.Context, message *paho.Publish) {
+		handlerCtx = ctx
+	}))
+	client.SubscribeWithId(context.Background(), "devices/+/config", func(Client, []string, uint16, interface{}, error) {})
+
+	options := newPublishOptions(0)
+	injectTraceProperties(ctx, options)
+	client.router.Route(newPahoPublish("devices/device1/status", []byte(`"online"`), options).Packet())
+	routeMessage(client, "devices/device2/config", `not json`)
+
+	// The handler continues the trace of the publisher
+	require.NotNil(t, handlerCtx)
+	assert.Equal(t, trace.SpanContextFromContext(ctx).TraceID(), trace.SpanContextFromContext(handlerCtx).TraceID())
+
+	metrics := metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(context.Background(), &metrics))
+
+	received := map[string]int64{}
+	decodeFailures := map[string]int64{}
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok {
+				continue
+			}
+
+			for _, point := range sum.DataPoints {
+				filter, _ := point.Attributes.Value(attribute.Key(attrTopicFilter))
+				switch m.Name {
+				case mqttMessagesReceivedTotal:
+					received[filter.AsString()] += point.Value
+				case mqttDecodeFailuresTotal:
+					decodeFailures[filter.AsString()] += point.Value
+				}
+			}
+		}
+	}
+
+	// Labelled by the topic filter instead of the topic
+	assert.Equal(t, map[string]int64{"devices/+/status": 1, "devices/+/config": 1}, received)
+	assert.Equal(t, map[string]int64{"devices/+/config": 1}, decodeFailures)
+}
@@ -0,0 +1,42 @@
+package mqtt
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// TypedHandler is a handler receiving the payload decoded into T. With the protobuf codec, T must be a message pointer.
+type TypedHandler[T any] func(client Client, topicIds []string, payloadId uint16, payload T, err error)
+
+// SubscribeTyped subscribes to a topic and decodes the payloads into T. The handler receives the levels of the actual topic as ids.
+func SubscribeTyped[T any](ctx context.Context, client Client, topic Topic, handler TypedHandler[T], opts ...SubscribeOpt) error {
+	return client.Subscribe(ctx, topic, newTypedHandler(handler), append(opts, withTarget(newTypedTarget[T]))...)
+}
+
+// SubscribeWithIdTyped subscribes to a topic and decodes the payloads into T. The handler receives the values of the
+// topic wildcards as ids.
+func SubscribeWithIdTyped[T any](ctx context.Context, client Client, topic Topic, handler TypedHandler[T], opts ...SubscribeOpt) {
+	client.SubscribeWithId(ctx, topic, newTypedHandler(handler), append(opts, withTarget(newTypedTarget[T]))...)
+}
+
+func newTypedTarget[T any]() interface{} {
+	return new(T)
+}
+
+// newTypedHandler converts the decoded target to T
+func newTypedHandler[T any](handler TypedHandler[T]) Handler {
+	return func(client Client, topicIds []string, payloadId uint16, payload interface{}, err error) {
+		var value T
+		if err == nil {
+			target, ok := payload.(*T)
+			if ok {
+				value = *target
+			} else {
+				err = errors.Wrapf(ErrUnsupportedType, "expected a %T payload, got %T", value, payload)
+			}
+		}
+
+		handler(client, topicIds, payloadId, value, err)
+	}
+}
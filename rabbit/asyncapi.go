@@ -0,0 +1,278 @@
+package rabbit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	asyncAPIVersion    = "3.0.0"
+	amqpBindingVersion = "0.3.0"
+	protobufMediaType  = "application/vnd.google.protobuf"
+)
+
+// AsyncAPIInfo is the general information about the service in the AsyncAPI document
+type AsyncAPIInfo struct {
+	// Title defaults to the service exchange
+	Title string
+	// Version defaults to 1.0.0
+	Version     string
+	Description string
+}
+
+type asyncAPIDocument struct {
+	AsyncAPI   string                        `json:"asyncapi"`
+	Info       asyncAPIInfo                  `json:"info"`
+	Servers    map[string]asyncAPIServer     `json:"servers,omitempty"`
+	Channels   map[string]*asyncAPIChannel   `json:"channels"`
+	Operations map[string]*asyncAPIOperation `json:"operations"`
+	Components asyncAPIComponents            `json:"components"`
+}
+
+type asyncAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type asyncAPIServer struct {
+	Host     string `json:"host"`
+	Protocol string `json:"protocol"`
+	Pathname string `json:"pathname,omitempty"`
+}
+
+type asyncAPIRef struct {
+	Ref string `json:"$ref"`
+}
+
+type asyncAPIChannel struct {
+	Address  string                 `json:"address"`
+	Messages map[string]asyncAPIRef `json:"messages,omitempty"`
+	Bindings asyncAPIChannelBinding `json:"bindings"`
+}
+
+type asyncAPIChannelBinding struct {
+	AMQP asyncAPIAMQPChannelBinding `json:"amqp"`
+}
+
+type asyncAPIAMQPChannelBinding struct {
+	Is             string               `json:"is"`
+	Exchange       asyncAPIAMQPExchange `json:"exchange"`
+	BindingVersion string               `json:"bindingVersion"`
+}
+
+type asyncAPIAMQPExchange struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type asyncAPIOperation struct {
+	Action   string        `json:"action"`
+	Channel  asyncAPIRef   `json:"channel"`
+	Messages []asyncAPIRef `json:"messages,omitempty"`
+
+	// Queue is the queue the consumer is bound with, there is no standard AsyncAPI field for it
+	Queue *asyncAPIQueue `json:"x-queue,omitempty"`
+}
+
+type asyncAPIQueue struct {
+	Name       string `json:"name"`
+	Durable    bool   `json:"durable"`
+	Exclusive  bool   `json:"exclusive"`
+	AutoDelete bool   `json:"autoDelete"`
+}
+
+type asyncAPIComponents struct {
+	Messages map[string]asyncAPIMessage `json:"messages,omitempty"`
+	Schemas  map[string]*jsonSchema     `json:"schemas,omitempty"`
+}
+
+type asyncAPIMessage struct {
+	Name        string      `json:"name"`
+	ContentType string      `json:"contentType"`
+	Payload     *jsonSchema `json:"payload"`
+}
+
+// AsyncAPIDocument renders an AsyncAPI 3.0 JSON document from the consumers and the declared publications.
+// The message payloads are described with JSON Schemas of the protojson encoding of the messages.
+func (c *Rabbit) AsyncAPIDocument(info AsyncAPIInfo) ([]byte, error) {
+	if info.Title == "" {
+		info.Title = c.Exchange.String()
+	}
+
+	document := newAsyncAPIDocument(info, c.configuration.URL, c.ConsumerFactory.catalog.listConsumers(), c.Publisher.catalog.listPublications())
+	return json.MarshalIndent(document, "", "  ")
+}
+
+// WriteAsyncAPIDocument writes the AsyncAPI document to the file
+func (c *Rabbit) WriteAsyncAPIDocument(path string, info AsyncAPIInfo) error {
+	document, err := c.AsyncAPIDocument(info)
+	if err != nil {
+		return errors.Wrap(err, "failed to render AsyncAPI document")
+	}
+
+	return os.WriteFile(path, document, 0o644)
+}
+
+// AsyncAPIHandler serves the AsyncAPI document. The document is rendered on every request, so consumers created
+// after the handler are included.
+func (c *Rabbit) AsyncAPIHandler(info AsyncAPIInfo) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		document, err := c.AsyncAPIDocument(info)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(document)
+	})
+}
+
+// Consumers returns all the consumers created by the ConsumerFactory, except the internal reply consumer
+func (c *Rabbit) Consumers() []ConsumerInfo {
+	return c.ConsumerFactory.catalog.listConsumers()
+}
+
+// Publications returns all the publications declared on the PublisherPool
+func (c *Rabbit) Publications() []PublicationInfo {
+	return c.Publisher.catalog.listPublications()
+}
+
+func newAsyncAPIDocument(info AsyncAPIInfo, serverURL string, consumers []ConsumerInfo, publications []PublicationInfo) *asyncAPIDocument {
+	if info.Version == "" {
+		info.Version = "1.0.0"
+	}
+
+	document := &asyncAPIDocument{
+		AsyncAPI: asyncAPIVersion,
+		Info: asyncAPIInfo{
+			Title:       info.Title,
+			Version:     info.Version,
+			Description: info.Description,
+		},
+		Servers:    asyncAPIServers(serverURL),
+		Channels:   map[string]*asyncAPIChannel{},
+		Operations: map[string]*asyncAPIOperation{},
+		Components: asyncAPIComponents{
+			Messages: map[string]asyncAPIMessage{},
+			Schemas:  map[string]*jsonSchema{},
+		},
+	}
+
+	for _, publication := range publications {
+		channelId, messages := document.addChannel(publication.Exchange, publication.Topic, publication.Message)
+		document.addOperation("send."+channelId, &asyncAPIOperation{
+			Action:   "send",
+			Channel:  asyncAPIRef{Ref: "#/channels/" + channelId},
+			Messages: messages,
+		})
+	}
+
+	for _, consumer := range consumers {
+		channelId, messages := document.addChannel(consumer.Exchange, consumer.RoutingKey, consumer.Message)
+		document.addOperation("receive."+asyncAPIKey(consumer.Queue), &asyncAPIOperation{
+			Action:   "receive",
+			Channel:  asyncAPIRef{Ref: "#/channels/" + channelId},
+			Messages: messages,
+			Queue: &asyncAPIQueue{
+				Name:       consumer.Queue,
+				Durable:    consumer.Durable,
+				Exclusive:  consumer.Exclusive,
+				AutoDelete: consumer.AutoDelete,
+			},
+		})
+	}
+
+	return document
+}
+
+// asyncAPIServers describes the broker without the credentials
+func asyncAPIServers(serverURL string) map[string]asyncAPIServer {
+	parsed, err := url.Parse(serverURL)
+	if err != nil || parsed.Host == "" {
+		return nil
+	}
+
+	pathname := ""
+	if vhost := strings.TrimPrefix(parsed.Path, "/"); vhost != "" {
+		pathname = "/" + vhost
+	}
+
+	return map[string]asyncAPIServer{
+		"rabbitmq": {
+			Host:     parsed.Host,
+			Protocol: parsed.Scheme,
+			Pathname: pathname,
+		},
+	}
+}
+
+// addChannel adds the channel of the routing key and the message to the document, if it does not exist yet.
+// Returns the channel id and the references to the channel messages.
+func (d *asyncAPIDocument) addChannel(exchange Exchange, routingKey Topic, message proto.Message) (string, []asyncAPIRef) {
+	channelId := asyncAPIKey(exchange.String() + "." + routingKey.String())
+
+	channel, ok := d.Channels[channelId]
+	if !ok {
+		channel = &asyncAPIChannel{
+			Address:  routingKey.String(),
+			Messages: map[string]asyncAPIRef{},
+			Bindings: asyncAPIChannelBinding{
+				AMQP: asyncAPIAMQPChannelBinding{
+					Is:             "routingKey",
+					Exchange:       asyncAPIAMQPExchange{Name: exchange.String(), Type: string(TopicExchange)},
+					BindingVersion: amqpBindingVersion,
+				},
+			},
+		}
+		d.Channels[channelId] = channel
+	}
+
+	if message == nil {
+		return channelId, nil
+	}
+
+	descriptor := message.ProtoReflect().Descriptor()
+	messageId := string(descriptor.FullName())
+	if _, ok := d.Components.Messages[messageId]; !ok {
+		addMessageSchemas(descriptor, d.Components.Schemas)
+		d.Components.Messages[messageId] = asyncAPIMessage{
+			Name:        string(descriptor.Name()),
+			ContentType: protobufMediaType,
+			Payload:     &jsonSchema{Ref: schemaRef(descriptor)},
+		}
+	}
+
+	channel.Messages[messageId] = asyncAPIRef{Ref: "#/components/messages/" + messageId}
+	return channelId, []asyncAPIRef{{Ref: "#/channels/" + channelId + "/messages/" + messageId}}
+}
+
+// addOperation adds the operation, suffixing the id with a number if it already exists
+func (d *asyncAPIDocument) addOperation(operationId string, operation *asyncAPIOperation) {
+	id := operationId
+	for i := 2; ; i++ {
+		if _, ok := d.Operations[id]; !ok {
+			break
+		}
+		id = operationId + "." + strconv.Itoa(i)
+	}
+
+	d.Operations[id] = operation
+}
+
+var asyncAPIKeyReplacer = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// asyncAPIKey converts the name to a valid AsyncAPI component key. The routing key wildcards are spelled out.
+func asyncAPIKey(name string) string {
+	name = strings.NewReplacer("*", "star", "#", "hash").Replace(name)
+	return asyncAPIKeyReplacer.ReplaceAllString(name, "_")
+}
@@ -0,0 +1,128 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	timestamp := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	capturedHeaders := amqp.Table{
+		"error":     true,
+		"retries":   int32(3),
+		"priority":  byte(1),
+		"offset":    int64(1 << 40),
+		"ratio":     float32(0.5),
+		"payload":   []byte{0x00, 0xff},
+		"sentAt":    timestamp,
+		"amount":    amqp.Decimal{Scale: 2, Value: 1050},
+		"missing":   nil,
+		"nested":    amqp.Table{"key": "value", "attempt": int16(2)},
+		"hops":      []any{"CENTRAL", int32(1), amqp.Table{"uint": uint32(7)}},
+		"signature": "text",
+	}
+	deliveries := make(chan amqp.Delivery, 2)
+	deliveries <- amqp.Delivery{
+		Exchange:      "CENTRAL",
+		RoutingKey:    "CENTRAL.chargePoint.status",
+		ContentType:   "application/vnd.google.protobuf",
+		CorrelationId: "correlationId",
+		ReplyTo:       "CENTRAL.REPLY.host",
+		Timestamp:     timestamp,
+		Headers:       capturedHeaders,
+		Body:          []byte{0x00, 0x01, 0xff},
+	}
+	deliveries <- amqp.Delivery{
+		Exchange:   "CENTRAL",
+		RoutingKey: "OTHER.topic",
+		Body:       []byte("second"),
+	}
+
+	buffer := &bytes.Buffer{}
+	recorded, err := record(context.Background(), deliveries, 2, buffer)
+	require.NoError(t, err)
+	assert.Equal(t, 2, recorded)
+
+	type published struct {
+		exchange   string
+		routingKey string
+		publishing amqp.Publishing
+	}
+	replayedMessages := []published{}
+
+	options := ReplayOptions{
+		Exchange: "STAGING",
+		Rewrites: []TopicRewrite{{Prefix: "CENTRAL.", Replacement: "STAGING."}},
+	}
+	replayed, err := replay(context.Background(), options, buffer, func(exchange, routingKey string, publishing amqp.Publishing) error {
+		replayedMessages = append(replayedMessages, published{exchange, routingKey, publishing})
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, replayed)
+
+	first := replayedMessages[0]
+	assert.Equal(t, "STAGING", first.exchange)
+	assert.Equal(t, "STAGING.chargePoint.status", first.routingKey)
+	assert.Equal(t, []byte{0x00, 0x01, 0xff}, first.publishing.Body)
+	assert.Equal(t, "correlationId", first.publishing.CorrelationId)
+	assert.Equal(t, "CENTRAL.REPLY.host", first.publishing.ReplyTo)
+	assert.True(t, first.publishing.Timestamp.Equal(timestamp))
+	// The headers are replayed with the captured types
+	assert.Equal(t, capturedHeaders, first.publishing.Headers)
+	assert.NoError(t, first.publishing.Headers.Validate())
+
+	second := replayedMessages[1]
+	assert.Equal(t, "OTHER.topic", second.routingKey)
+	assert.Equal(t, []byte("second"), second.publishing.Body)
+}
+
+func TestRecord_ContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	recorded, err := record(ctx, make(chan amqp.Delivery), 0, &bytes.Buffer{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, recorded)
+}
+
+func TestReplay_InvalidLine(t *testing.T) {
+	replayed, err := replay(context.Background(), ReplayOptions{}, bytes.NewBufferString("not json\n"), func(string, string, amqp.Publishing) error {
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 0, replayed)
+}
+
+func TestParseTopicRewrite(t *testing.T) {
+	rewrite, err := ParseTopicRewrite("CENTRAL.=STAGING.")
+	require.NoError(t, err)
+	assert.Equal(t, TopicRewrite{Prefix: "CENTRAL.", Replacement: "STAGING."}, rewrite)
+
+	rewrite, err = ParseTopicRewrite("CENTRAL.=")
+	require.NoError(t, err)
+	assert.Equal(t, TopicRewrite{Prefix: "CENTRAL."}, rewrite)
+
+	_, err = ParseTopicRewrite("CENTRAL")
+	assert.Error(t, err)
+
+	_, err = ParseTopicRewrite("=STAGING")
+	assert.Error(t, err)
+}
+
+func TestReplayOptions_RoutingKey(t *testing.T) {
+	options := ReplayOptions{Rewrites: []TopicRewrite{
+		{Prefix: "A.B", Replacement: "X"},
+		{Prefix: "A", Replacement: "Y"},
+	}}
+
+	assert.Equal(t, "X.C", options.routingKey("A.B.C"))
+	assert.Equal(t, "Y.C", options.routingKey("A.C"))
+	assert.Equal(t, "B.C", options.routingKey("B.C"))
+}
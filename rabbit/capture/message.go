@@ -0,0 +1,255 @@
+package capture
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Message is a captured delivery, stored as a single JSON line
+type Message struct {
+	// CapturedAt is the time the delivery was received by the recorder
+	CapturedAt time.Time  `json:"capturedAt"`
+	Exchange   string     `json:"exchange"`
+	RoutingKey string     `json:"routingKey"`
+	Headers    Headers    `json:"headers,omitempty"`
+	Properties Properties `json:"properties"`
+
+	// Body is the base64 encoded message body
+	Body string `json:"body"`
+}
+
+// Properties are the AMQP basic properties of a captured message
+type Properties struct {
+	ContentType     string    `json:"contentType,omitempty"`
+	ContentEncoding string    `json:"contentEncoding,omitempty"`
+	DeliveryMode    uint8     `json:"deliveryMode,omitempty"`
+	Priority        uint8     `json:"priority,omitempty"`
+	CorrelationId   string    `json:"correlationId,omitempty"`
+	ReplyTo         string    `json:"replyTo,omitempty"`
+	Expiration      string    `json:"expiration,omitempty"`
+	MessageId       string    `json:"messageId,omitempty"`
+	Timestamp       time.Time `json:"timestamp,omitempty"`
+	Type            string    `json:"type,omitempty"`
+	UserId          string    `json:"userId,omitempty"`
+	AppId           string    `json:"appId,omitempty"`
+}
+
+// NewMessage creates a message from a delivery
+func NewMessage(delivery amqp.Delivery, capturedAt time.Time) Message {
+	return Message{
+		CapturedAt: capturedAt,
+		Exchange:   delivery.Exchange,
+		RoutingKey: delivery.RoutingKey,
+		Headers:    Headers(delivery.Headers),
+		Properties: Properties{
+			ContentType:     delivery.ContentType,
+			ContentEncoding: delivery.ContentEncoding,
+			DeliveryMode:    delivery.DeliveryMode,
+			Priority:        delivery.Priority,
+			CorrelationId:   delivery.CorrelationId,
+			ReplyTo:         delivery.ReplyTo,
+			Expiration:      delivery.Expiration,
+			MessageId:       delivery.MessageId,
+			Timestamp:       delivery.Timestamp,
+			Type:            delivery.Type,
+			UserId:          delivery.UserId,
+			AppId:           delivery.AppId,
+		},
+		Body: base64.StdEncoding.EncodeToString(delivery.Body),
+	}
+}
+
+// Publishing converts the message back to an AMQP publishing
+func (m Message) Publishing() (amqp.Publishing, error) {
+	body, err := base64.StdEncoding.DecodeString(m.Body)
+	if err != nil {
+		return amqp.Publishing{}, err
+	}
+
+	return amqp.Publishing{
+		Headers:         amqp.Table(m.Headers),
+		ContentType:     m.Properties.ContentType,
+		ContentEncoding: m.Properties.ContentEncoding,
+		DeliveryMode:    m.Properties.DeliveryMode,
+		Priority:        m.Properties.Priority,
+		CorrelationId:   m.Properties.CorrelationId,
+		ReplyTo:         m.Properties.ReplyTo,
+		Expiration:      m.Properties.Expiration,
+		MessageId:       m.Properties.MessageId,
+		Timestamp:       m.Properties.Timestamp,
+		Type:            m.Properties.Type,
+		UserId:          m.Properties.UserId,
+		AppId:           m.Properties.AppId,
+		Body:            body,
+	}, nil
+}
+
+// Headers are the AMQP headers of a captured message. Each value is stored with its AMQP type, e.g.
+// {"retries": {"type": "int32", "value": 3}}, so the headers are replayed with the captured types.
+type Headers amqp.Table
+
+// typedValue is a header value with its AMQP type
+type typedValue struct {
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+// rawTypedValue is a typedValue with the value not yet decoded
+type rawTypedValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// MarshalJSON stores the header values with their types
+func (h Headers) MarshalJSON() ([]byte, error) {
+	values := make(map[string]typedValue, len(h))
+	for key, value := range h {
+		typed, err := newTypedValue(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "header %q", key)
+		}
+
+		values[key] = typed
+	}
+
+	return json.Marshal(values)
+}
+
+// UnmarshalJSON restores the header values with their types
+func (h *Headers) UnmarshalJSON(data []byte) error {
+	var values map[string]rawTypedValue
+	err := json.Unmarshal(data, &values)
+	if err != nil {
+		return err
+	}
+
+	headers := make(Headers, len(values))
+	for key, raw := range values {
+		headers[key], err = raw.decode()
+		if err != nil {
+			return errors.Wrapf(err, "header %q", key)
+		}
+	}
+
+	*h = headers
+	return nil
+}
+
+func newTypedValue(value any) (typedValue, error) {
+	switch v := value.(type) {
+	case nil:
+		return typedValue{Type: "void"}, nil
+	case bool:
+		return typedValue{Type: "bool", Value: v}, nil
+	case int8:
+		return typedValue{Type: "int8", Value: v}, nil
+	case byte:
+		return typedValue{Type: "uint8", Value: v}, nil
+	case int16:
+		return typedValue{Type: "int16", Value: v}, nil
+	case uint16:
+		return typedValue{Type: "uint16", Value: v}, nil
+	case int32:
+		return typedValue{Type: "int32", Value: v}, nil
+	case uint32:
+		return typedValue{Type: "uint32", Value: v}, nil
+	case int:
+		// Sent as a long integer
+		return typedValue{Type: "int64", Value: int64(v)}, nil
+	case int64:
+		return typedValue{Type: "int64", Value: v}, nil
+	case float32:
+		return typedValue{Type: "float32", Value: v}, nil
+	case float64:
+		return typedValue{Type: "float64", Value: v}, nil
+	case string:
+		return typedValue{Type: "string", Value: v}, nil
+	case []byte:
+		return typedValue{Type: "bytes", Value: v}, nil
+	case amqp.Decimal:
+		return typedValue{Type: "decimal", Value: v}, nil
+	case time.Time:
+		return typedValue{Type: "timestamp", Value: v}, nil
+	case amqp.Table:
+		return typedValue{Type: "table", Value: Headers(v)}, nil
+	case []any:
+		values := make([]typedValue, len(v))
+		for i, item := range v {
+			typed, err := newTypedValue(item)
+			if err != nil {
+				return typedValue{}, err
+			}
+
+			values[i] = typed
+		}
+
+		return typedValue{Type: "array", Value: values}, nil
+	default:
+		return typedValue{}, errors.Errorf("unsupported header type %T", value)
+	}
+}
+
+func (v rawTypedValue) decode() (any, error) {
+	switch v.Type {
+	case "void":
+		return nil, nil
+	case "bool":
+		return decodeAs[bool](v.Value)
+	case "int8":
+		return decodeAs[int8](v.Value)
+	case "uint8":
+		return decodeAs[byte](v.Value)
+	case "int16":
+		return decodeAs[int16](v.Value)
+	case "uint16":
+		return decodeAs[uint16](v.Value)
+	case "int32":
+		return decodeAs[int32](v.Value)
+	case "uint32":
+		return decodeAs[uint32](v.Value)
+	case "int64":
+		return decodeAs[int64](v.Value)
+	case "float32":
+		return decodeAs[float32](v.Value)
+	case "float64":
+		return decodeAs[float64](v.Value)
+	case "string":
+		return decodeAs[string](v.Value)
+	case "bytes":
+		return decodeAs[[]byte](v.Value)
+	case "decimal":
+		return decodeAs[amqp.Decimal](v.Value)
+	case "timestamp":
+		return decodeAs[time.Time](v.Value)
+	case "table":
+		headers, err := decodeAs[Headers](v.Value)
+		return amqp.Table(headers), err
+	case "array":
+		items, err := decodeAs[[]rawTypedValue](v.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		values := make([]any, len(items))
+		for i, item := range items {
+			values[i], err = item.decode()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return values, nil
+	default:
+		return nil, errors.Errorf("unsupported header type %q", v.Type)
+	}
+}
+
+func decodeAs[T any](data json.RawMessage) (T, error) {
+	var value T
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
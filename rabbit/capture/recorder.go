@@ -0,0 +1,83 @@
+package capture
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// RecordOptions configures what is recorded
+type RecordOptions struct {
+	// Exchange is the exchange the temporary queue is bound to
+	Exchange string
+
+	// BindingKey is the binding pattern, e.g. "CENTRAL.#"
+	BindingKey string
+
+	// Limit stops the recording after the number of messages. Zero means no limit.
+	Limit int
+}
+
+// Record binds a temporary queue to the exchange and writes every delivery as a JSON line to the writer,
+// until the context is cancelled or the limit is reached. Returns the number of recorded messages.
+// The temporary queue is exclusive and deleted when the recording stops.
+func Record(ctx context.Context, url string, options RecordOptions, w io.Writer) (int, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to connect to rabbitmq")
+	}
+	defer conn.Close()
+
+	channel, err := conn.Channel()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to open a channel")
+	}
+	defer channel.Close()
+
+	// Let the server name the queue
+	queue, err := channel.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to declare the capture queue")
+	}
+
+	err = channel.QueueBind(queue.Name, options.BindingKey, options.Exchange, false, nil)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to bind the capture queue")
+	}
+
+	deliveries, err := channel.Consume(queue.Name, "", true, true, false, false, nil)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to consume from the capture queue")
+	}
+
+	return record(ctx, deliveries, options.Limit, w)
+}
+
+func record(ctx context.Context, deliveries <-chan amqp.Delivery, limit int, w io.Writer) (int, error) {
+	encoder := json.NewEncoder(w)
+	recorded := 0
+
+	for limit == 0 || recorded < limit {
+		select {
+		case <-ctx.Done():
+			return recorded, nil
+		case delivery, ok := <-deliveries:
+			if !ok {
+				return recorded, errors.New("capture channel closed")
+			}
+
+			err := encoder.Encode(NewMessage(delivery, time.Now()))
+			if err != nil {
+				return recorded, errors.Wrap(err, "failed to write the message")
+			}
+
+			recorded++
+		}
+	}
+
+	return recorded, nil
+}
@@ -0,0 +1,125 @@
+package capture
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"golang.org/x/time/rate"
+)
+
+// TopicRewrite replaces the Prefix of a routing key with the Replacement
+type TopicRewrite struct {
+	Prefix      string
+	Replacement string
+}
+
+// ParseTopicRewrite parses a rewrite in the "prefix=replacement" format
+func ParseTopicRewrite(rewrite string) (TopicRewrite, error) {
+	prefix, replacement, found := strings.Cut(rewrite, "=")
+	if !found || prefix == "" {
+		return TopicRewrite{}, fmt.Errorf("invalid topic rewrite %q, expected prefix=replacement", rewrite)
+	}
+
+	return TopicRewrite{Prefix: prefix, Replacement: replacement}, nil
+}
+
+// ReplayOptions configures how a capture is replayed
+type ReplayOptions struct {
+	// Exchange overrides the captured exchange. If empty, messages are published to the exchange they were captured from.
+	Exchange string
+
+	// Rate limits the number of published messages per second. Zero means no limit.
+	Rate float64
+
+	// Rewrites are applied to the routing keys in order, only the first matching rewrite is applied
+	Rewrites []TopicRewrite
+}
+
+// routingKey returns the routing key after applying the rewrites
+func (o ReplayOptions) routingKey(routingKey string) string {
+	for _, rewrite := range o.Rewrites {
+		if strings.HasPrefix(routingKey, rewrite.Prefix) {
+			return rewrite.Replacement + strings.TrimPrefix(routingKey, rewrite.Prefix)
+		}
+	}
+
+	return routingKey
+}
+
+func (o ReplayOptions) exchange(exchange string) string {
+	if o.Exchange != "" {
+		return o.Exchange
+	}
+
+	return exchange
+}
+
+// Replay publishes the captured messages from the reader in order. Returns the number of published messages.
+func Replay(ctx context.Context, url string, options ReplayOptions, r io.Reader) (int, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to connect to rabbitmq")
+	}
+	defer conn.Close()
+
+	channel, err := conn.Channel()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to open a channel")
+	}
+	defer channel.Close()
+
+	return replay(ctx, options, r, func(exchange, routingKey string, publishing amqp.Publishing) error {
+		return channel.PublishWithContext(ctx, exchange, routingKey, false, false, publishing)
+	})
+}
+
+type publishFunc func(exchange, routingKey string, publishing amqp.Publishing) error
+
+func replay(ctx context.Context, options ReplayOptions, r io.Reader, publish publishFunc) (int, error) {
+	limiter := rate.NewLimiter(rate.Inf, 1)
+	if options.Rate > 0 {
+		limiter = rate.NewLimiter(rate.Limit(options.Rate), 1)
+	}
+
+	scanner := bufio.NewScanner(r)
+	// Allow messages up to the default RabbitMQ max message size, base64 encoded
+	scanner.Buffer(make([]byte, 0, 64*1024), 256*1024*1024)
+
+	replayed := 0
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+
+		var message Message
+		err := json.Unmarshal(scanner.Bytes(), &message)
+		if err != nil {
+			return replayed, errors.Wrapf(err, "failed to parse message on line %d", line)
+		}
+
+		publishing, err := message.Publishing()
+		if err != nil {
+			return replayed, errors.Wrapf(err, "failed to decode the body on line %d", line)
+		}
+
+		err = limiter.Wait(ctx)
+		if err != nil {
+			return replayed, err
+		}
+
+		err = publish(options.exchange(message.Exchange), options.routingKey(message.RoutingKey), publishing)
+		if err != nil {
+			return replayed, errors.Wrapf(err, "failed to publish message on line %d", line)
+		}
+
+		replayed++
+	}
+
+	return replayed, scanner.Err()
+}
@@ -0,0 +1,77 @@
+package rabbit
+
+import (
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// ConsumerInfo describes a consumer created by the ConsumerFactory
+type ConsumerInfo struct {
+	Exchange   Exchange
+	RoutingKey Topic
+	Queue      string
+	Durable    bool
+	Exclusive  bool
+	AutoDelete bool
+
+	// Message is the type of the consumed messages, nil if it was not set with WithMessageType
+	Message proto.Message
+}
+
+// PublicationInfo describes a topic the service publishes to, declared with PublisherPool.DeclarePublication
+type PublicationInfo struct {
+	Exchange Exchange
+	Topic    Topic
+	Message  proto.Message
+}
+
+// catalog keeps track of the consumers and the declared publications of the service
+type catalog struct {
+	mu           sync.RWMutex
+	consumers    []ConsumerInfo
+	publications []PublicationInfo
+}
+
+func newCatalog() *catalog {
+	return &catalog{}
+}
+
+func (c *catalog) addConsumer(consumer ConsumerInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.consumers = append(c.consumers, consumer)
+}
+
+// addPublication adds the publication, unless the topic was already declared
+func (c *catalog) addPublication(publication PublicationInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, existing := range c.publications {
+		if existing.Exchange == publication.Exchange && existing.Topic == publication.Topic {
+			return
+		}
+	}
+
+	c.publications = append(c.publications, publication)
+}
+
+func (c *catalog) listConsumers() []ConsumerInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	consumers := make([]ConsumerInfo, len(c.consumers))
+	copy(consumers, c.consumers)
+	return consumers
+}
+
+func (c *catalog) listPublications() []PublicationInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	publications := make([]PublicationInfo, len(c.publications))
+	copy(publications, c.publications)
+	return publications
+}
@@ -0,0 +1,311 @@
+package rabbit
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/GLCharge/otelzap"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitOpenError is returned when a message is not published, because the circuit breaker for the topic is open
+type CircuitOpenError struct {
+	Topic Topic
+
+	// RetryAfter is the time until the circuit breaker lets a trial request through, zero while the trial requests of
+	// a half-open circuit are in flight
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	// The trial requests of a half-open circuit have no known end
+	if e.RetryAfter <= 0 {
+		return fmt.Sprintf("circuit breaker for topic %s is open", e.Topic)
+	}
+
+	return fmt.Sprintf("circuit breaker for topic %s is open, retry after %s", e.Topic, e.RetryAfter)
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitHalfOpen
+	CircuitOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitHalfOpen:
+		return "half-open"
+	case CircuitOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerConfig configures the circuit breaker of a topic
+type CircuitBreakerConfig struct {
+	// FailureRateThreshold is the ratio (0-1) of failed requests in a window that opens the circuit. Defaults to 0.5.
+	FailureRateThreshold float64
+
+	// MinimumRequests is the number of requests in a window required before the failure rate is evaluated. Defaults to 10.
+	MinimumRequests int
+
+	// Window is the period in which the requests are counted while the circuit is closed. Defaults to 30s.
+	Window time.Duration
+
+	// OpenTimeout is the time the circuit stays open before letting trial requests through. Defaults to 30s.
+	OpenTimeout time.Duration
+
+	// HalfOpenRequests is the number of successful trial requests required to close the circuit. Defaults to 1.
+	HalfOpenRequests int
+}
+
+func (c CircuitBreakerConfig) withDefaults() CircuitBreakerConfig {
+	if c.FailureRateThreshold <= 0 {
+		c.FailureRateThreshold = 0.5
+	}
+
+	if c.MinimumRequests <= 0 {
+		c.MinimumRequests = 10
+	}
+
+	if c.Window <= 0 {
+		c.Window = 30 * time.Second
+	}
+
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = 30 * time.Second
+	}
+
+	if c.HalfOpenRequests <= 0 {
+		c.HalfOpenRequests = 1
+	}
+
+	return c
+}
+
+// circuitBreaker tracks the failures of a single topic
+type circuitBreaker struct {
+	topic    Topic
+	config   CircuitBreakerConfig
+	onChange func(topic Topic, from, to CircuitState)
+	now      func() time.Time
+
+	mu    sync.Mutex
+	state CircuitState
+	// generation changes on every state transition, so the requests allowed in an earlier state are not recorded
+	generation       uint64
+	windowStart      time.Time
+	openedAt         time.Time
+	requests         int
+	failures         int
+	halfOpenInFlight int
+	halfOpenSuccess  int
+}
+
+func newCircuitBreaker(topic Topic, config CircuitBreakerConfig, onChange func(topic Topic, from, to CircuitState)) *circuitBreaker {
+	return &circuitBreaker{
+		topic:       topic,
+		config:      config.withDefaults(),
+		onChange:    onChange,
+		now:         time.Now,
+		state:       CircuitClosed,
+		windowStart: time.Now(),
+	}
+}
+
+// circuitPermit is a request allowed by the circuit breaker, recorded in the state it was allowed in
+type circuitPermit struct {
+	breaker    *circuitBreaker
+	generation uint64
+}
+
+// record records the outcome of the request. Does nothing for a request without a circuit breaker.
+func (p circuitPermit) record(success bool) {
+	if p.breaker == nil {
+		return
+	}
+
+	p.breaker.record(p.generation, success)
+}
+
+// allow returns an error if the request should not be made
+func (cb *circuitBreaker) allow() (circuitPermit, error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	now := cb.now()
+
+	switch cb.state {
+	case CircuitOpen:
+		retryAfter := cb.openedAt.Add(cb.config.OpenTimeout).Sub(now)
+		if retryAfter > 0 {
+			return circuitPermit{}, &CircuitOpenError{Topic: cb.topic, RetryAfter: retryAfter}
+		}
+
+		cb.setState(CircuitHalfOpen)
+		fallthrough
+	case CircuitHalfOpen:
+		if cb.halfOpenInFlight >= cb.config.HalfOpenRequests {
+			return circuitPermit{}, &CircuitOpenError{Topic: cb.topic}
+		}
+
+		cb.halfOpenInFlight++
+	default:
+		if now.Sub(cb.windowStart) >= cb.config.Window {
+			cb.resetWindow()
+		}
+	}
+
+	return circuitPermit{breaker: cb, generation: cb.generation}, nil
+}
+
+// record records the outcome of a request allowed in the generation. Requests allowed before the last state
+// transition are ignored, e.g. a request allowed while closed does not count as a trial of the half-open circuit.
+func (cb *circuitBreaker) record(generation uint64, success bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if generation != cb.generation {
+		return
+	}
+
+	switch cb.state {
+	case CircuitHalfOpen:
+		cb.halfOpenInFlight--
+		if !success {
+			cb.setState(CircuitOpen)
+			return
+		}
+
+		cb.halfOpenSuccess++
+		if cb.halfOpenSuccess >= cb.config.HalfOpenRequests {
+			cb.setState(CircuitClosed)
+		}
+	case CircuitClosed:
+		cb.requests++
+		if !success {
+			cb.failures++
+		}
+
+		if cb.requests < cb.config.MinimumRequests {
+			return
+		}
+
+		if float64(cb.failures)/float64(cb.requests) >= cb.config.FailureRateThreshold {
+			cb.setState(CircuitOpen)
+		}
+	default:
+		// No requests are allowed while the circuit is open
+	}
+}
+
+func (cb *circuitBreaker) getState() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return cb.state
+}
+
+// setState transitions the circuit to a new state. Must be called with the lock held.
+func (cb *circuitBreaker) setState(state CircuitState) {
+	previous := cb.state
+	cb.state = state
+	cb.generation++
+
+	switch state {
+	case CircuitOpen:
+		cb.openedAt = cb.now()
+	case CircuitHalfOpen:
+		cb.halfOpenInFlight = 0
+		cb.halfOpenSuccess = 0
+	case CircuitClosed:
+		cb.resetWindow()
+	}
+
+	if cb.onChange != nil && previous != state {
+		cb.onChange(cb.topic, previous, state)
+	}
+}
+
+func (cb *circuitBreaker) resetWindow() {
+	cb.windowStart = cb.now()
+	cb.requests = 0
+	cb.failures = 0
+}
+
+// circuitBreakers holds a circuit breaker for each topic, created on first use
+type circuitBreakers struct {
+	defaultConfig *CircuitBreakerConfig
+	topicConfigs  map[Topic]CircuitBreakerConfig
+	metrics       rabbitMetrics
+	logger        *otelzap.Logger
+
+	mu       sync.Mutex
+	breakers map[Topic]*circuitBreaker
+}
+
+func newCircuitBreakers(defaultConfig *CircuitBreakerConfig, topicConfigs map[Topic]CircuitBreakerConfig, metrics rabbitMetrics, logger *otelzap.Logger) *circuitBreakers {
+	return &circuitBreakers{
+		defaultConfig: defaultConfig,
+		topicConfigs:  topicConfigs,
+		metrics:       metrics,
+		logger:        logger,
+		breakers:      make(map[Topic]*circuitBreaker),
+	}
+}
+
+// get returns the circuit breaker for the topic or nil, if the topic has no circuit breaker
+func (cbs *circuitBreakers) get(topic Topic) *circuitBreaker {
+	cbs.mu.Lock()
+	defer cbs.mu.Unlock()
+
+	breaker, ok := cbs.breakers[topic]
+	if ok {
+		return breaker
+	}
+
+	config, ok := cbs.topicConfigs[topic]
+	if !ok {
+		if cbs.defaultConfig == nil {
+			return nil
+		}
+		config = *cbs.defaultConfig
+	}
+
+	breaker = newCircuitBreaker(topic, config, cbs.onStateChange)
+	cbs.breakers[topic] = breaker
+	cbs.metrics.RecordCircuitBreakerState(string(topic), CircuitClosed)
+
+	return breaker
+}
+
+func (cbs *circuitBreakers) onStateChange(topic Topic, from, to CircuitState) {
+	cbs.metrics.RecordCircuitBreakerState(string(topic), to)
+
+	logger := cbs.logger.With(
+		zap.String("topic", string(topic)),
+		zap.String("from", from.String()),
+		zap.String("to", to.String()),
+	)
+
+	if to == CircuitOpen {
+		logger.Warn("Circuit breaker opened")
+		return
+	}
+
+	logger.Info("Circuit breaker state changed")
+}
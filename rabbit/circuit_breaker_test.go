@@ -0,0 +1,162 @@
+package rabbit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xBlaz3kx/DevX/observability"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.now
+}
+
+func newTestCircuitBreaker(config CircuitBreakerConfig) (*circuitBreaker, *fakeClock, *[]CircuitState) {
+	clock := &fakeClock{now: time.Now()}
+	transitions := &[]CircuitState{}
+
+	breaker := newCircuitBreaker("service.topic", config, func(topic Topic, from, to CircuitState) {
+		*transitions = append(*transitions, to)
+	})
+	breaker.now = clock.Now
+	breaker.windowStart = clock.now
+
+	return breaker, clock, transitions
+}
+
+// allowAndRecord makes a request allowed by the circuit breaker with the outcome
+func allowAndRecord(t *testing.T, breaker *circuitBreaker, success bool) {
+	t.Helper()
+
+	permit, err := breaker.allow()
+	require.NoError(t, err)
+	permit.record(success)
+}
+
+func TestCircuitBreaker_Opens(t *testing.T) {
+	breaker, _, transitions := newTestCircuitBreaker(CircuitBreakerConfig{
+		FailureRateThreshold: 0.5,
+		MinimumRequests:      4,
+		OpenTimeout:          time.Minute,
+	})
+
+	// Not enough requests to evaluate the failure rate
+	for i := 0; i < 3; i++ {
+		allowAndRecord(t, breaker, false)
+	}
+	assert.Equal(t, CircuitClosed, breaker.getState())
+
+	allowAndRecord(t, breaker, true)
+	assert.Equal(t, CircuitOpen, breaker.getState())
+	assert.Equal(t, []CircuitState{CircuitOpen}, *transitions)
+
+	_, err := breaker.allow()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	var openErr *CircuitOpenError
+	require.True(t, errors.As(err, &openErr))
+	assert.EqualValues(t, "service.topic", openErr.Topic)
+	assert.Equal(t, time.Minute, openErr.RetryAfter)
+}
+
+func TestCircuitBreaker_HalfOpen(t *testing.T) {
+	breaker, clock, transitions := newTestCircuitBreaker(CircuitBreakerConfig{
+		MinimumRequests:  1,
+		OpenTimeout:      time.Minute,
+		HalfOpenRequests: 1,
+	})
+
+	allowAndRecord(t, breaker, false)
+	assert.Equal(t, CircuitOpen, breaker.getState())
+
+	// After the timeout, a single trial request is allowed
+	clock.now = clock.now.Add(time.Minute)
+	trial, err := breaker.allow()
+	require.NoError(t, err)
+	assert.Equal(t, CircuitHalfOpen, breaker.getState())
+
+	_, err = breaker.allow()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, "circuit breaker for topic service.topic is open", err.Error())
+
+	// Failed trial opens the circuit again
+	trial.record(false)
+	assert.Equal(t, CircuitOpen, breaker.getState())
+
+	// Successful trial closes the circuit
+	clock.now = clock.now.Add(time.Minute)
+	allowAndRecord(t, breaker, true)
+	assert.Equal(t, CircuitClosed, breaker.getState())
+
+	assert.Equal(t, []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitOpen, CircuitHalfOpen, CircuitClosed}, *transitions)
+}
+
+func TestCircuitBreaker_StaleResults(t *testing.T) {
+	breaker, clock, _ := newTestCircuitBreaker(CircuitBreakerConfig{
+		MinimumRequests:  1,
+		OpenTimeout:      time.Minute,
+		HalfOpenRequests: 1,
+	})
+
+	// A slow request is allowed while the circuit is closed
+	slow, err := breaker.allow()
+	require.NoError(t, err)
+
+	allowAndRecord(t, breaker, false)
+	clock.now = clock.now.Add(time.Minute)
+	trial, err := breaker.allow()
+	require.NoError(t, err)
+
+	// The slow request finishing does not count as the trial
+	slow.record(true)
+	assert.Equal(t, CircuitHalfOpen, breaker.getState())
+	_, err = breaker.allow()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	trial.record(true)
+	assert.Equal(t, CircuitClosed, breaker.getState())
+}
+
+func TestCircuitBreaker_WindowReset(t *testing.T) {
+	breaker, clock, _ := newTestCircuitBreaker(CircuitBreakerConfig{
+		FailureRateThreshold: 0.5,
+		MinimumRequests:      2,
+		Window:               time.Second,
+	})
+
+	allowAndRecord(t, breaker, false)
+
+	// The failure from the previous window is forgotten
+	clock.now = clock.now.Add(time.Second)
+	allowAndRecord(t, breaker, true)
+	allowAndRecord(t, breaker, true)
+
+	assert.Equal(t, CircuitClosed, breaker.getState())
+}
+
+func TestCircuitBreakers_Get(t *testing.T) {
+	metrics, err := newRabbitMetrics("")
+	require.NoError(t, err)
+
+	breakers := newCircuitBreakers(nil, map[Topic]CircuitBreakerConfig{
+		"service.rpc": {MinimumRequests: 5},
+	}, metrics, observability.NewNoopObservability().Log())
+
+	assert.Nil(t, breakers.get("service.other"))
+
+	breaker := breakers.get("service.rpc")
+	require.NotNil(t, breaker)
+	assert.Equal(t, 5, breaker.config.MinimumRequests)
+	assert.Same(t, breaker, breakers.get("service.rpc"))
+
+	// With a default config, every topic gets a circuit breaker
+	breakers = newCircuitBreakers(&CircuitBreakerConfig{}, nil, metrics, observability.NewNoopObservability().Log())
+	assert.NotNil(t, breakers.get("service.other"))
+}
@@ -0,0 +1,278 @@
+package rabbit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// concurrencyLimiter limits the number of handlers running at the same time. The limit can be changed at runtime.
+type concurrencyLimiter struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	limit    int
+	inFlight int
+
+	// peakInFlight is the highest number of handlers running at the same time since the last reset
+	peakInFlight int
+}
+
+func newConcurrencyLimiter(limit int) *concurrencyLimiter {
+	limiter := &concurrencyLimiter{limit: limit}
+	limiter.cond = sync.NewCond(&limiter.mu)
+	return limiter
+}
+
+// acquire blocks until a handler is allowed to run
+func (l *concurrencyLimiter) acquire() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for l.inFlight >= l.limit {
+		l.cond.Wait()
+	}
+
+	l.inFlight++
+	l.peakInFlight = max(l.peakInFlight, l.inFlight)
+}
+
+func (l *concurrencyLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	l.cond.Signal()
+}
+
+func (l *concurrencyLimiter) setLimit(limit int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = limit
+	l.cond.Broadcast()
+}
+
+func (l *concurrencyLimiter) getLimit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.limit
+}
+
+// resetPeak returns the peak number of running handlers and resets it to the current number
+func (l *concurrencyLimiter) resetPeak() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	peak := l.peakInFlight
+	l.peakInFlight = l.inFlight
+	return peak
+}
+
+// AdaptiveConcurrency configures the adaptive concurrency of a consumer
+type AdaptiveConcurrency struct {
+	// MinRoutines is the lowest number of handlers running at the same time
+	MinRoutines int
+
+	// MaxRoutines is the highest number of handlers running at the same time
+	MaxRoutines int
+
+	// TargetLatency is the highest average handler latency before the concurrency is decreased
+	TargetLatency time.Duration
+
+	// MaxErrorRate is the highest ratio (0-1) of failed handlers before the concurrency is decreased. Defaults to 0.1.
+	MaxErrorRate float64
+
+	// Window is the period over which the latency and errors are observed before adjusting the concurrency. Defaults to 5s.
+	Window time.Duration
+}
+
+func (a AdaptiveConcurrency) withDefaults() AdaptiveConcurrency {
+	a.MinRoutines = max(a.MinRoutines, 1)
+	a.MaxRoutines = max(a.MaxRoutines, a.MinRoutines)
+
+	if a.MaxErrorRate <= 0 {
+		a.MaxErrorRate = 0.1
+	}
+
+	if a.Window <= 0 {
+		a.Window = 5 * time.Second
+	}
+
+	return a
+}
+
+// adaptiveLimiter adjusts the concurrency limit based on the observed handler latency and error rate.
+// The limit is increased additively while the handlers are healthy and saturated and decreased multiplicatively otherwise.
+type adaptiveLimiter struct {
+	*concurrencyLimiter
+	config   AdaptiveConcurrency
+	onChange func(limit int)
+
+	mu           sync.Mutex
+	windowStart  time.Time
+	samples      int
+	failures     int
+	totalLatency time.Duration
+}
+
+func newAdaptiveLimiter(config AdaptiveConcurrency, onChange func(limit int)) *adaptiveLimiter {
+	config = config.withDefaults()
+
+	return &adaptiveLimiter{
+		concurrencyLimiter: newConcurrencyLimiter(config.MinRoutines),
+		config:             config,
+		onChange:           onChange,
+		windowStart:        time.Now(),
+	}
+}
+
+// observe records the outcome of a handler and adjusts the limit at the end of each window
+func (a *adaptiveLimiter) observe(latency time.Duration, failed bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.samples++
+	a.totalLatency += latency
+	if failed {
+		a.failures++
+	}
+
+	if time.Since(a.windowStart) < a.config.Window {
+		return
+	}
+
+	a.adjust()
+
+	a.windowStart = time.Now()
+	a.samples = 0
+	a.failures = 0
+	a.totalLatency = 0
+}
+
+// adjust calculates the new limit from the current window. Must be called with the lock held.
+func (a *adaptiveLimiter) adjust() {
+	if a.samples == 0 {
+		return
+	}
+
+	var (
+		limit          = a.getLimit()
+		newLimit       = limit
+		peak           = a.resetPeak()
+		errorRate      = float64(a.failures) / float64(a.samples)
+		averageLatency = a.totalLatency / time.Duration(a.samples)
+	)
+
+	switch {
+	case errorRate > a.config.MaxErrorRate, a.config.TargetLatency > 0 && averageLatency > a.config.TargetLatency:
+		newLimit = max(a.config.MinRoutines, limit*3/4)
+	case peak >= limit:
+		// Only increase the limit if the handlers are using all the capacity
+		newLimit = min(a.config.MaxRoutines, limit+1)
+	}
+
+	if newLimit == limit {
+		return
+	}
+
+	a.setLimit(newLimit)
+	if a.onChange != nil {
+		a.onChange(newLimit)
+	}
+}
+
+// Directions of the routine scaling decisions
+const (
+	scaleUp   = "up"
+	scaleDown = "down"
+)
+
+// scaleDecision is the outcome of a single routine scaling evaluation
+type scaleDecision struct {
+	from      int
+	to        int
+	direction string
+	reason    string
+}
+
+// routineScaler scales the number of active handler routines between the minimum and maximum.
+// It scales up while the handlers are saturated and there is a backlog in the queue and scales down when the handlers are idle.
+type routineScaler struct {
+	*concurrencyLimiter
+	minRoutines int
+	maxRoutines int
+	interval    time.Duration
+
+	// backlog returns the number of messages ready in the queue, false if unknown
+	backlog func() (int, bool)
+	onScale func(decision scaleDecision)
+}
+
+func newRoutineScaler(minRoutines, maxRoutines int, interval time.Duration, backlog func() (int, bool), onScale func(decision scaleDecision)) *routineScaler {
+	minRoutines = max(minRoutines, 1)
+	maxRoutines = max(maxRoutines, minRoutines)
+
+	return &routineScaler{
+		concurrencyLimiter: newConcurrencyLimiter(minRoutines),
+		minRoutines:        minRoutines,
+		maxRoutines:        maxRoutines,
+		interval:           interval,
+		backlog:            backlog,
+		onScale:            onScale,
+	}
+}
+
+// start evaluates the scaling every interval until the context is cancelled, so an idle consumer also scales down
+func (s *routineScaler) start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.evaluate()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// evaluate scales the routines based on the peak usage since the last evaluation and the queue backlog
+func (s *routineScaler) evaluate() {
+	backlog, backlogKnown := s.backlog()
+	decision, scaled := decideScale(s.getLimit(), s.resetPeak(), backlog, backlogKnown, s.minRoutines, s.maxRoutines)
+	if !scaled {
+		return
+	}
+
+	s.setLimit(decision.to)
+	if s.onScale != nil {
+		s.onScale(decision)
+	}
+}
+
+// decideScale calculates the new number of routines. The routines are doubled while all of them are busy and there is
+// a backlog (or the backlog is unknown), and reduced halfway towards the peak usage while the routines are underused
+// and the queue is empty (or the backlog is unknown).
+func decideScale(limit, peak, backlog int, backlogKnown bool, minRoutines, maxRoutines int) (scaleDecision, bool) {
+	decision := scaleDecision{from: limit, to: limit}
+	saturated := peak >= limit
+
+	switch {
+	case saturated && backlogKnown && backlog > 0:
+		decision.to = min(maxRoutines, limit*2)
+		decision.direction = scaleUp
+		decision.reason = "backlog"
+	case saturated && !backlogKnown:
+		decision.to = min(maxRoutines, limit*2)
+		decision.direction = scaleUp
+		decision.reason = "saturated"
+	case !saturated && (!backlogKnown || backlog == 0):
+		decision.to = max(minRoutines, (limit+peak)/2)
+		decision.direction = scaleDown
+		decision.reason = "idle"
+	}
+
+	return decision, decision.to != decision.from
+}
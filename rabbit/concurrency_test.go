@@ -0,0 +1,182 @@
+package rabbit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	limiter := newConcurrencyLimiter(1)
+	limiter.acquire()
+
+	acquired := make(chan struct{})
+	go func() {
+		limiter.acquire()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquired over the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Raising the limit should unblock the waiting routine
+	limiter.setLimit(2)
+
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("not acquired after raising the limit")
+	}
+
+	assert.Equal(t, 2, limiter.resetPeak())
+
+	// The peak of the next window starts at the number of running handlers
+	limiter.release()
+	limiter.release()
+	assert.Equal(t, 2, limiter.resetPeak())
+	assert.Equal(t, 0, limiter.resetPeak())
+}
+
+func TestAdaptiveLimiter_Increase(t *testing.T) {
+	limits := []int{}
+	limiter := newAdaptiveLimiter(AdaptiveConcurrency{
+		MinRoutines:   1,
+		MaxRoutines:   2,
+		TargetLatency: time.Second,
+		Window:        time.Nanosecond,
+	}, func(limit int) {
+		limits = append(limits, limit)
+	})
+
+	// Saturated and healthy, should increase up to the max
+	for i := 0; i < 3; i++ {
+		limiter.acquire()
+		limiter.observe(time.Millisecond, false)
+		limiter.release()
+	}
+
+	assert.Equal(t, 2, limiter.getLimit())
+	assert.Equal(t, []int{2}, limits)
+}
+
+func TestAdaptiveLimiter_Decrease(t *testing.T) {
+	limiter := newAdaptiveLimiter(AdaptiveConcurrency{
+		MinRoutines:   2,
+		MaxRoutines:   10,
+		TargetLatency: 100 * time.Millisecond,
+		Window:        time.Nanosecond,
+	}, nil)
+	limiter.setLimit(8)
+
+	// Too slow
+	limiter.observe(time.Second, false)
+	assert.Equal(t, 6, limiter.getLimit())
+
+	// Too many errors
+	limiter.observe(time.Millisecond, true)
+	assert.Equal(t, 4, limiter.getLimit())
+
+	// Never below the min
+	limiter.observe(time.Millisecond, true)
+	limiter.observe(time.Millisecond, true)
+	assert.Equal(t, 2, limiter.getLimit())
+}
+
+func TestAdaptiveConcurrency_Defaults(t *testing.T) {
+	config := AdaptiveConcurrency{MinRoutines: 0, MaxRoutines: 0}.withDefaults()
+
+	assert.Equal(t, 1, config.MinRoutines)
+	assert.Equal(t, 1, config.MaxRoutines)
+	assert.Equal(t, 0.1, config.MaxErrorRate)
+	assert.Equal(t, 5*time.Second, config.Window)
+}
+
+func TestDecideScale(t *testing.T) {
+	tests := []struct {
+		name         string
+		limit        int
+		peak         int
+		backlog      int
+		backlogKnown bool
+		expected     int
+		direction    string
+	}{
+		{name: "saturated with backlog", limit: 2, peak: 2, backlog: 100, backlogKnown: true, expected: 4, direction: scaleUp},
+		{name: "saturated without known backlog", limit: 2, peak: 2, expected: 4, direction: scaleUp},
+		{name: "scale up capped at max", limit: 6, peak: 6, backlog: 100, backlogKnown: true, expected: 8, direction: scaleUp},
+		{name: "saturated with empty queue", limit: 4, peak: 4, backlog: 0, backlogKnown: true, expected: 4},
+		{name: "idle with empty queue", limit: 8, peak: 2, backlog: 0, backlogKnown: true, expected: 5, direction: scaleDown},
+		{name: "idle without known backlog", limit: 4, peak: 0, expected: 2, direction: scaleDown},
+		{name: "scale down capped at min", limit: 2, peak: 0, backlog: 0, backlogKnown: true, expected: 2},
+		{name: "underused with backlog", limit: 4, peak: 3, backlog: 10, backlogKnown: true, expected: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, scaled := decideScale(tt.limit, tt.peak, tt.backlog, tt.backlogKnown, 2, 8)
+			assert.Equal(t, tt.expected, decision.to)
+			assert.Equal(t, tt.expected != tt.limit, scaled)
+			if scaled {
+				assert.Equal(t, tt.direction, decision.direction)
+			}
+		})
+	}
+}
+
+func TestRoutineScaler(t *testing.T) {
+	var decisions []scaleDecision
+	backlog := 50
+	scaler := newRoutineScaler(1, 4, 0, func() (int, bool) { return backlog, true }, func(decision scaleDecision) {
+		decisions = append(decisions, decision)
+	})
+	assert.Equal(t, 1, scaler.getLimit())
+
+	// A saturated handler with a backlog scales up
+	scaler.acquire()
+	scaler.evaluate()
+	scaler.release()
+	assert.Equal(t, 2, scaler.getLimit())
+
+	// An idle consumer with an empty queue scales down
+	backlog = 0
+	scaler.resetPeak()
+	scaler.evaluate()
+	assert.Equal(t, 1, scaler.getLimit())
+
+	if assert.Len(t, decisions, 2) {
+		assert.Equal(t, scaleDecision{from: 1, to: 2, direction: scaleUp, reason: "backlog"}, decisions[0])
+		assert.Equal(t, scaleDecision{from: 2, to: 1, direction: scaleDown, reason: "idle"}, decisions[1])
+	}
+}
+
+func TestRoutineScaler_ScalesDownWhenIdle(t *testing.T) {
+	scaled := make(chan scaleDecision, 10)
+	scaler := newRoutineScaler(1, 8, 10*time.Millisecond, func() (int, bool) { return 0, true }, func(decision scaleDecision) {
+		scaled <- decision
+	})
+	scaler.setLimit(8)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		scaler.start(ctx)
+		close(stopped)
+	}()
+
+	// Without any deliveries, the routines are reduced on every interval down to the minimum
+	require.Eventually(t, func() bool { return scaler.getLimit() == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, scaleDecision{from: 8, to: 4, direction: scaleDown, reason: "idle"}, <-scaled)
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("scaler was not stopped")
+	}
+}
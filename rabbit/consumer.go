@@ -2,19 +2,29 @@ package rabbit
 
 import (
 	"context"
+	"time"
 
 	"github.com/wagslane/go-rabbitmq"
 	"github.com/xBlaz3kx/DevX/observability"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
 )
 
+// routineScaleInterval is how often the consumer routines are scaled
+const routineScaleInterval = 5 * time.Second
+
 type HandlerFunc func(ctx context.Context, d rabbitmq.Delivery) (action rabbitmq.Action)
 
 type ConsumerFactory struct {
 	connection *rabbitmq.Conn
 	opts       ConsumerOpts
 	exchange   Exchange
+	queues     *queueRegistry
+	catalog    *catalog
+
+	// queueStats is set when the queue stats are collected, used for scaling the routines
+	queueStats *queueStatsCollector
 
 	// Observability
 	obs     observability.Observability
@@ -36,6 +46,8 @@ func NewConsumerFactory(connection *rabbitmq.Conn, exchange Exchange, metrics ra
 		obs:        obs.WithSpanKind(trace.SpanKindConsumer),
 		opts:       consumerOptions,
 		metrics:    metrics,
+		queues:     newQueueRegistry(),
+		catalog:    newCatalog(),
 	}
 
 	return consumer
@@ -63,12 +75,81 @@ func (cm *ConsumerFactory) NewConsumer(exchange Exchange, topic Topic, queueName
 		rabbitmq.WithConsumerOptionsRoutingKey(string(topic)),
 	}
 
+	// Limit the rate of handled messages
+	var rateLimiter *rate.Limiter
+	if consumerOptions.rateLimit > 0 {
+		rateLimiter = rate.NewLimiter(consumerOptions.rateLimit, consumerOptions.rateBurst)
+	}
+
+	// Run the maximum number of routines and let the adaptive limiter decide how many can handle messages
+	var concurrency *adaptiveLimiter
+	if consumerOptions.adaptive != nil {
+		concurrency = newAdaptiveLimiter(*consumerOptions.adaptive, func(limit int) {
+			logger.Debug("Adjusted consumer concurrency", zap.Int("limit", limit))
+			cm.metrics.RecordConcurrencyLimit(queueName, limit)
+		})
+		cm.metrics.RecordConcurrencyLimit(queueName, concurrency.getLimit())
+
+		options = append(options,
+			rabbitmq.WithConsumerOptionsConcurrency(consumerOptions.adaptive.MaxRoutines),
+			rabbitmq.WithConsumerOptionsQOSPrefetch(consumerOptions.adaptive.MaxRoutines),
+		)
+	}
+
+	// Run the maximum number of routines and scale the number of routines that can handle messages
+	var scaler *routineScaler
+	if concurrency == nil && consumerOptions.maxRoutines > 0 {
+		scaler = newRoutineScaler(
+			consumerOptions.minRoutines,
+			consumerOptions.maxRoutines,
+			routineScaleInterval,
+			func() (int, bool) { return cm.backlog(queueName) },
+			func(decision scaleDecision) {
+				logger.Info("Scaled consumer routines",
+					zap.Int("from", decision.from),
+					zap.Int("to", decision.to),
+					zap.String("reason", decision.reason),
+				)
+				cm.metrics.RecordConcurrencyLimit(queueName, decision.to)
+				cm.metrics.IncrementConsumerScaled(queueName, decision.direction)
+			},
+		)
+		cm.metrics.RecordConcurrencyLimit(queueName, scaler.getLimit())
+
+		options = append(options,
+			rabbitmq.WithConsumerOptionsConcurrency(consumerOptions.maxRoutines),
+			rabbitmq.WithConsumerOptionsQOSPrefetch(consumerOptions.maxRoutines),
+		)
+	}
+
 	if durable {
 		options = append(options, rabbitmq.WithConsumerOptionsQueueDurable, rabbitmq.WithConsumerOptionsQueueQuorum)
 	}
 
+	if consumerOptions.exclusive {
+		options = append(options, rabbitmq.WithConsumerOptionsQueueExclusive)
+	}
+
+	if consumerOptions.autoDelete {
+		options = append(options, rabbitmq.WithConsumerOptionsQueueAutoDelete)
+	}
+
 	// Set up the handler for the message
 	rabbitHandler := func(d rabbitmq.Delivery) rabbitmq.Action {
+		if rateLimiter != nil {
+			_ = rateLimiter.Wait(context.Background())
+		}
+
+		if concurrency != nil {
+			concurrency.acquire()
+			defer concurrency.release()
+		}
+
+		if scaler != nil {
+			scaler.acquire()
+			defer scaler.release()
+		}
+
 		timeoutCtx, cancel := context.WithTimeout(context.Background(), cm.opts.eventTimeout)
 		defer cancel()
 
@@ -79,8 +160,14 @@ func (cm *ConsumerFactory) NewConsumer(exchange Exchange, topic Topic, queueName
 		cm.metrics.IncrementMessagesDelivered(string(topic))
 
 		// Call the handler function
+		start := time.Now()
 		action := handler(ctx, d)
 
+		if concurrency != nil {
+			failed := action == rabbitmq.NackRequeue || action == rabbitmq.NackDiscard || timeoutCtx.Err() != nil
+			concurrency.observe(time.Since(start), failed)
+		}
+
 		// Depending on the response, increment the appropriate metric
 		switch action {
 		case rabbitmq.Ack:
@@ -105,8 +192,30 @@ func (cm *ConsumerFactory) NewConsumer(exchange Exchange, topic Topic, queueName
 		return nil, err
 	}
 
+	// Keep track of the queue for the queue stats
+	cm.trackQueue(queueName, consumerOptions)
+
+	if !consumerOptions.internal {
+		cm.catalog.addConsumer(ConsumerInfo{
+			Exchange:   exchange,
+			RoutingKey: topic,
+			Queue:      queueName,
+			Durable:    durable,
+			Exclusive:  consumerOptions.exclusive,
+			AutoDelete: consumerOptions.autoDelete,
+			Message:    consumerOptions.messageType,
+		})
+	}
+
+	// Scale the routines until the consumer is closed
+	scalerCtx, stopScaler := context.WithCancel(context.Background())
+	if scaler != nil {
+		go scaler.start(scalerCtx)
+	}
+
 	go func() {
 		err = consumer.Run(rabbitHandler)
+		stopScaler()
 		if err != nil {
 			logger.Panic("Error running rabbit consumer", zap.Error(err))
 		}
@@ -114,3 +223,55 @@ func (cm *ConsumerFactory) NewConsumer(exchange Exchange, topic Topic, queueName
 
 	return consumer, nil
 }
+
+// trackQueue adds the queue to the queue stats. Exclusive queues are skipped, as they cannot be passive declared from
+// the connection of the queue stats collector.
+func (cm *ConsumerFactory) trackQueue(queueName string, consumerOptions ConsumerOpts) {
+	if consumerOptions.exclusive {
+		return
+	}
+
+	cm.queues.add(queueName)
+}
+
+// backlog returns the number of messages ready for delivery in the queue, false if the queue stats are not collected
+func (cm *ConsumerFactory) backlog(queueName string) (int, bool) {
+	if cm.queueStats == nil {
+		return 0, false
+	}
+
+	stats, ok := cm.queueStats.get(queueName)
+	if !ok {
+		return 0, false
+	}
+
+	if stats.Detailed {
+		return stats.MessagesReady, true
+	}
+
+	return stats.Messages, true
+}
+
+// SubscribeBroadcast subscribes to a notification topic on the GlobalNotificationExchange, so that every instance of the
+// service receives every notification, instead of competing for them on a shared queue.
+// Each subscription gets a uniquely named exclusive queue, which is deleted when the instance disconnects and
+// re-declared and re-bound with the same name after a reconnect.
+func (cm *ConsumerFactory) SubscribeBroadcast(topic Topic, handler HandlerFunc, opts ...ConsumerOpt) (*rabbitmq.Consumer, error) {
+	queueName, err := newBroadcastQueueName(cm.exchange)
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append([]ConsumerOpt{WithExclusiveQueue(true), WithAutoDeleteQueue(true)}, opts...)
+	return cm.NewConsumer(GlobalNotificationExchange, topic, queueName, handler, false, opts...)
+}
+
+// newBroadcastQueueName creates a queue name unique to the subscription
+func newBroadcastQueueName(serviceExchange Exchange) (string, error) {
+	instanceId, err := newInstanceId()
+	if err != nil {
+		return "", err
+	}
+
+	return NewTopic(serviceExchange).AddWord(broadcastBase).AddWord(instanceId).Build().String(), nil
+}
@@ -1,10 +1,26 @@
 package rabbit
 
-import "time"
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/protobuf/proto"
+)
 
 type ConsumerOpts struct {
 	eventTimeout time.Duration
 	routines     int
+	exclusive    bool
+	autoDelete   bool
+	rateLimit    rate.Limit
+	rateBurst    int
+	adaptive     *AdaptiveConcurrency
+	minRoutines  int
+	maxRoutines  int
+	messageType  proto.Message
+
+	// internal consumers are not listed in the catalog
+	internal bool
 }
 
 type ConsumerOpt func(*ConsumerOpts)
@@ -27,3 +43,59 @@ func WithRoutines(routines int) ConsumerOpt {
 		c.routines = routines
 	}
 }
+
+// WithExclusiveQueue declares the queue as exclusive to the connection. The queue is deleted when the connection closes.
+func WithExclusiveQueue(exclusive bool) ConsumerOpt {
+	return func(c *ConsumerOpts) {
+		c.exclusive = exclusive
+	}
+}
+
+// WithAutoDeleteQueue declares the queue as auto-delete. The queue is deleted when the last consumer unsubscribes.
+func WithAutoDeleteQueue(autoDelete bool) ConsumerOpt {
+	return func(c *ConsumerOpts) {
+		c.autoDelete = autoDelete
+	}
+}
+
+// WithRateLimit limits the number of messages handled per second with a token bucket of the given burst size
+func WithRateLimit(messagesPerSecond float64, burst int) ConsumerOpt {
+	return func(c *ConsumerOpts) {
+		c.rateLimit = rate.Limit(messagesPerSecond)
+		c.rateBurst = max(burst, 1)
+	}
+}
+
+// WithAdaptiveConcurrency adjusts the number of active handler routines based on the handler latency and error rate.
+// It overrides the number of routines set with WithRoutines.
+func WithAdaptiveConcurrency(config AdaptiveConcurrency) ConsumerOpt {
+	return func(c *ConsumerOpts) {
+		config = config.withDefaults()
+		c.adaptive = &config
+	}
+}
+
+// WithRoutineRange scales the number of active handler routines between the minimum and the maximum, based on the
+// queue backlog and the handler saturation. The backlog is only known if the queue stats are collected (see WithQueueStats),
+// otherwise the routines are scaled on saturation alone. It overrides the number of routines set with WithRoutines
+// and is ignored if adaptive concurrency is enabled.
+func WithRoutineRange(minRoutines, maxRoutines int) ConsumerOpt {
+	return func(c *ConsumerOpts) {
+		c.minRoutines = max(minRoutines, 1)
+		c.maxRoutines = max(maxRoutines, c.minRoutines)
+	}
+}
+
+// WithMessageType sets the type of the consumed messages, which is used to describe the consumer in the AsyncAPI document
+func WithMessageType(message proto.Message) ConsumerOpt {
+	return func(c *ConsumerOpts) {
+		c.messageType = message
+	}
+}
+
+// withInternal hides the consumer from the catalog
+func withInternal() ConsumerOpt {
+	return func(c *ConsumerOpts) {
+		c.internal = true
+	}
+}
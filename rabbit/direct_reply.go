@@ -0,0 +1,154 @@
+package rabbit
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/xBlaz3kx/DevX/observability"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// directReplyQueue is the RabbitMQ pseudo-queue used for direct reply-to
+// https://www.rabbitmq.com/docs/direct-reply-to
+const directReplyQueue = "amq.rabbitmq.reply-to"
+
+// isDirectReplyTopic checks if the reply topic points to a direct reply-to consumer
+func isDirectReplyTopic(topic string) bool {
+	return strings.HasPrefix(topic, directReplyQueue)
+}
+
+// directReplyChannel publishes RPC requests and consumes their replies on the same channel, as required by direct reply-to.
+type directReplyChannel struct {
+	url       string
+	responses chan<- ReplyResponse
+	metrics   rabbitMetrics
+	obs       observability.Observability
+
+	mu      sync.Mutex
+	conn    *amqp.Connection
+	channel *amqp.Channel
+}
+
+func newDirectReplyChannel(url string, responses chan<- ReplyResponse, metrics rabbitMetrics, obs observability.Observability) (*directReplyChannel, error) {
+	directReply := &directReplyChannel{
+		url:       url,
+		responses: responses,
+		metrics:   metrics,
+		obs:       obs.WithSpanKind(trace.SpanKindProducer),
+	}
+
+	directReply.mu.Lock()
+	defer directReply.mu.Unlock()
+
+	err := directReply.ensureChannel()
+	if err != nil {
+		return nil, err
+	}
+
+	return directReply, nil
+}
+
+// ensureChannel (re)opens the channel and starts consuming the replies. Must be called with the lock held.
+func (dr *directReplyChannel) ensureChannel() error {
+	if dr.channel != nil && !dr.channel.IsClosed() {
+		return nil
+	}
+
+	if dr.conn == nil || dr.conn.IsClosed() {
+		conn, err := amqp.Dial(dr.url)
+		if err != nil {
+			return errors.Wrap(err, "failed to create a rabbit connection")
+		}
+		dr.conn = conn
+	}
+
+	channel, err := dr.conn.Channel()
+	if err != nil {
+		return errors.Wrap(err, "failed to open a channel")
+	}
+
+	// Direct reply-to consumers must be in no-ack mode
+	deliveries, err := channel.Consume(directReplyQueue, "", true, false, false, false, nil)
+	if err != nil {
+		_ = channel.Close()
+		return errors.Wrap(err, "failed to consume from the direct reply-to queue")
+	}
+
+	dr.channel = channel
+	go dr.consume(deliveries)
+
+	return nil
+}
+
+func (dr *directReplyChannel) consume(deliveries <-chan amqp.Delivery) {
+	for d := range deliveries {
+		dr.metrics.IncrementMessagesDelivered(directReplyQueue)
+
+		dr.responses <- ReplyResponse{
+			CorrelationId: d.CorrelationId,
+			Body:          d.Body,
+			Error:         isErrorReply(d.Headers),
+			Headers:       d.Headers,
+		}
+	}
+}
+
+// publish publishes a RPC request with the direct reply-to pseudo-queue as the reply topic
+func (dr *directReplyChannel) publish(ctx context.Context, topic Topic, message proto.Message, correlationID string, optionFuncs ...PublishOpt) error {
+	logger := dr.obs.Log().Ctx(ctx).With(zap.String("topic", string(topic)), zap.String("correlationId", correlationID))
+
+	// Apply options
+	publisherOptions := newPublisherOptions()
+	for _, optionFunc := range optionFuncs {
+		optionFunc(publisherOptions)
+	}
+
+	headers := getPublisherHeaders(ctx, publisherOptions)
+
+	payload, err := proto.Marshal(message)
+	if err != nil {
+		logger.Error("Error marshalling message", zap.Error(err))
+		return err
+	}
+
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+
+	err = dr.ensureChannel()
+	if err != nil {
+		return err
+	}
+
+	err = dr.channel.PublishWithContext(ctx, string(CentralExchange), string(topic), false, false, amqp.Publishing{
+		ContentType:   "application/vnd.google.protobuf",
+		CorrelationId: correlationID,
+		ReplyTo:       directReplyQueue,
+		Headers:       amqp.Table(headers),
+		Body:          payload,
+	})
+	if err != nil {
+		logger.Error("Error publishing a message", zap.Error(err))
+		return err
+	}
+
+	dr.metrics.IncrementMessagesPublished(string(topic))
+
+	logger.With(zap.Any("headers", headers)).Debug("Published message")
+	return nil
+}
+
+func (dr *directReplyChannel) close() error {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+
+	if dr.conn == nil || dr.conn.IsClosed() {
+		return nil
+	}
+
+	return dr.conn.Close()
+}
@@ -0,0 +1,134 @@
+package rabbit
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// jsonSchema is a JSON Schema (draft-07, as used by AsyncAPI) describing the protojson mapping of a message
+type jsonSchema struct {
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	ContentEncoding      string                 `json:"contentEncoding,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties,omitempty"`
+}
+
+// schemaRef returns the reference to the schema of the message in the AsyncAPI components
+func schemaRef(message protoreflect.MessageDescriptor) string {
+	return "#/components/schemas/" + string(message.FullName())
+}
+
+// addMessageSchemas converts the message and all the messages it references to JSON Schemas.
+// The schemas are added to the given map, keyed by the full name of the message.
+func addMessageSchemas(message protoreflect.MessageDescriptor, schemas map[string]*jsonSchema) {
+	name := string(message.FullName())
+	if _, ok := schemas[name]; ok {
+		return
+	}
+
+	schema := &jsonSchema{
+		Type:       "object",
+		Title:      string(message.Name()),
+		Properties: map[string]*jsonSchema{},
+	}
+	// Register the schema before converting the fields, so recursive messages terminate
+	schemas[name] = schema
+
+	fields := message.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		schema.Properties[field.JSONName()] = fieldSchema(field, schemas)
+	}
+}
+
+func fieldSchema(field protoreflect.FieldDescriptor, schemas map[string]*jsonSchema) *jsonSchema {
+	switch {
+	case field.IsMap():
+		return &jsonSchema{
+			Type:                 "object",
+			AdditionalProperties: singularSchema(field.MapValue(), schemas),
+		}
+	case field.IsList():
+		return &jsonSchema{
+			Type:  "array",
+			Items: singularSchema(field, schemas),
+		}
+	default:
+		return singularSchema(field, schemas)
+	}
+}
+
+// singularSchema returns the schema of a single value of the field, following the protojson encoding
+func singularSchema(field protoreflect.FieldDescriptor, schemas map[string]*jsonSchema) *jsonSchema {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return &jsonSchema{Type: "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return &jsonSchema{Type: "integer", Format: "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return &jsonSchema{Type: "integer", Format: "uint32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		// 64-bit integers are encoded as strings
+		return &jsonSchema{Type: "string", Format: "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return &jsonSchema{Type: "string", Format: "uint64"}
+	case protoreflect.FloatKind:
+		return &jsonSchema{Type: "number", Format: "float"}
+	case protoreflect.DoubleKind:
+		return &jsonSchema{Type: "number", Format: "double"}
+	case protoreflect.StringKind:
+		return &jsonSchema{Type: "string"}
+	case protoreflect.BytesKind:
+		return &jsonSchema{Type: "string", ContentEncoding: "base64"}
+	case protoreflect.EnumKind:
+		values := field.Enum().Values()
+		enum := make([]string, 0, values.Len())
+		for i := 0; i < values.Len(); i++ {
+			enum = append(enum, string(values.Get(i).Name()))
+		}
+		return &jsonSchema{Type: "string", Enum: enum}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if schema, ok := wellKnownSchema(field.Message()); ok {
+			return schema
+		}
+
+		addMessageSchemas(field.Message(), schemas)
+		return &jsonSchema{Ref: schemaRef(field.Message())}
+	default:
+		return &jsonSchema{}
+	}
+}
+
+// wellKnownSchema returns the schemas of the well-known types, which have a special JSON encoding
+func wellKnownSchema(message protoreflect.MessageDescriptor) (*jsonSchema, bool) {
+	switch message.FullName() {
+	case "google.protobuf.Timestamp":
+		return &jsonSchema{Type: "string", Format: "date-time"}, true
+	case "google.protobuf.Duration", "google.protobuf.FieldMask":
+		return &jsonSchema{Type: "string"}, true
+	case "google.protobuf.Struct", "google.protobuf.Empty", "google.protobuf.Any":
+		return &jsonSchema{Type: "object"}, true
+	case "google.protobuf.Value":
+		return &jsonSchema{}, true
+	case "google.protobuf.ListValue":
+		return &jsonSchema{Type: "array", Items: &jsonSchema{}}, true
+	case "google.protobuf.BoolValue":
+		return &jsonSchema{Type: "boolean"}, true
+	case "google.protobuf.StringValue":
+		return &jsonSchema{Type: "string"}, true
+	case "google.protobuf.BytesValue":
+		return &jsonSchema{Type: "string", ContentEncoding: "base64"}, true
+	case "google.protobuf.Int32Value", "google.protobuf.UInt32Value":
+		return &jsonSchema{Type: "integer"}, true
+	case "google.protobuf.Int64Value", "google.protobuf.UInt64Value":
+		return &jsonSchema{Type: "string"}, true
+	case "google.protobuf.FloatValue", "google.protobuf.DoubleValue":
+		return &jsonSchema{Type: "number"}, true
+	default:
+		return nil, false
+	}
+}
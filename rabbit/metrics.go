@@ -18,8 +18,22 @@ const (
 	rabbitMessagesRejectedTotal     = "rabbit_messages_rejected_total"
 	rabbitConsumersTotal            = "rabbit_consumers"
 	rabbitPublishersTotal           = "rabbit_publishers"
+	rabbitQueueMessages             = "rabbit_queue_messages"
+	rabbitQueueMessagesReady        = "rabbit_queue_messages_ready"
+	rabbitQueueMessagesUnacked      = "rabbit_queue_messages_unacknowledged"
+	rabbitQueueConsumers            = "rabbit_queue_consumers"
+	rabbitQueuePublishRate          = "rabbit_queue_publish_rate"
+	rabbitQueueDeliverRate          = "rabbit_queue_deliver_rate"
+	rabbitQueueAckRate              = "rabbit_queue_ack_rate"
+	rabbitQueueLagSeconds           = "rabbit_queue_lag_seconds"
+	rabbitConsumerConcurrencyLimit  = "rabbit_consumer_concurrency_limit"
+	rabbitConsumerScaledTotal       = "rabbit_consumer_scaled_total"
+	rabbitCircuitBreakerState       = "rabbit_circuit_breaker_state"
+	rabbitCircuitBreakerRejected    = "rabbit_circuit_breaker_rejected_total"
 
 	attrQueueName = "queue_name"
+	attrTopic     = "topic"
+	attrDirection = "direction"
 )
 
 type rabbitMetrics struct {
@@ -30,6 +44,22 @@ type rabbitMetrics struct {
 	messagesRejected     metric.Int64Counter
 	consumers            metric.Int64Gauge
 	publishers           metric.Int64Gauge
+
+	// Queue-level metrics, reported by the queue stats collector
+	queueMessages        metric.Int64Gauge
+	queueMessagesReady   metric.Int64Gauge
+	queueMessagesUnacked metric.Int64Gauge
+	queueConsumers       metric.Int64Gauge
+	queuePublishRate     metric.Float64Gauge
+	queueDeliverRate     metric.Float64Gauge
+	queueAckRate         metric.Float64Gauge
+	queueLagSeconds      metric.Float64Gauge
+
+	consumerConcurrencyLimit metric.Int64Gauge
+	consumerScaled           metric.Int64Counter
+
+	circuitBreakerState    metric.Int64Gauge
+	circuitBreakerRejected metric.Int64Counter
 }
 
 // Returns the metric name with the prefix
@@ -94,6 +124,94 @@ func newRabbitMetrics(prefix string) (metrics rabbitMetrics, err error) {
 		return rabbitMetrics{}, errors.Wrap(err, "failed to create rabbit_publishers_total metric")
 	}
 
+	if metrics.queueMessages, err = meter.Int64Gauge(
+		getMetricsPrefix(prefix, rabbitQueueMessages),
+		metric.WithDescription("Number of messages in the RabbitMQ queue"),
+	); err != nil {
+		return rabbitMetrics{}, errors.Wrap(err, "failed to create rabbit_queue_messages metric")
+	}
+
+	if metrics.queueMessagesReady, err = meter.Int64Gauge(
+		getMetricsPrefix(prefix, rabbitQueueMessagesReady),
+		metric.WithDescription("Number of messages in the RabbitMQ queue ready for delivery"),
+	); err != nil {
+		return rabbitMetrics{}, errors.Wrap(err, "failed to create rabbit_queue_messages_ready metric")
+	}
+
+	if metrics.queueMessagesUnacked, err = meter.Int64Gauge(
+		getMetricsPrefix(prefix, rabbitQueueMessagesUnacked),
+		metric.WithDescription("Number of delivered but unacknowledged messages in the RabbitMQ queue"),
+	); err != nil {
+		return rabbitMetrics{}, errors.Wrap(err, "failed to create rabbit_queue_messages_unacknowledged metric")
+	}
+
+	if metrics.queueConsumers, err = meter.Int64Gauge(
+		getMetricsPrefix(prefix, rabbitQueueConsumers),
+		metric.WithDescription("Number of consumers attached to the RabbitMQ queue"),
+	); err != nil {
+		return rabbitMetrics{}, errors.Wrap(err, "failed to create rabbit_queue_consumers metric")
+	}
+
+	if metrics.queuePublishRate, err = meter.Float64Gauge(
+		getMetricsPrefix(prefix, rabbitQueuePublishRate),
+		metric.WithDescription("Rate of messages published to the RabbitMQ queue"),
+		metric.WithUnit("{message}/s"),
+	); err != nil {
+		return rabbitMetrics{}, errors.Wrap(err, "failed to create rabbit_queue_publish_rate metric")
+	}
+
+	if metrics.queueDeliverRate, err = meter.Float64Gauge(
+		getMetricsPrefix(prefix, rabbitQueueDeliverRate),
+		metric.WithDescription("Rate of messages delivered from the RabbitMQ queue"),
+		metric.WithUnit("{message}/s"),
+	); err != nil {
+		return rabbitMetrics{}, errors.Wrap(err, "failed to create rabbit_queue_deliver_rate metric")
+	}
+
+	if metrics.queueAckRate, err = meter.Float64Gauge(
+		getMetricsPrefix(prefix, rabbitQueueAckRate),
+		metric.WithDescription("Rate of messages acknowledged on the RabbitMQ queue"),
+		metric.WithUnit("{message}/s"),
+	); err != nil {
+		return rabbitMetrics{}, errors.Wrap(err, "failed to create rabbit_queue_ack_rate metric")
+	}
+
+	if metrics.queueLagSeconds, err = meter.Float64Gauge(
+		getMetricsPrefix(prefix, rabbitQueueLagSeconds),
+		metric.WithDescription("Estimated time for the consumers to drain the RabbitMQ queue backlog"),
+		metric.WithUnit("s"),
+	); err != nil {
+		return rabbitMetrics{}, errors.Wrap(err, "failed to create rabbit_queue_lag_seconds metric")
+	}
+
+	if metrics.consumerConcurrencyLimit, err = meter.Int64Gauge(
+		getMetricsPrefix(prefix, rabbitConsumerConcurrencyLimit),
+		metric.WithDescription("Current number of handler routines allowed to run at the same time"),
+	); err != nil {
+		return rabbitMetrics{}, errors.Wrap(err, "failed to create rabbit_consumer_concurrency_limit metric")
+	}
+
+	if metrics.consumerScaled, err = meter.Int64Counter(
+		getMetricsPrefix(prefix, rabbitConsumerScaledTotal),
+		metric.WithDescription("Total number of times the consumer handler routines were scaled up or down"),
+	); err != nil {
+		return rabbitMetrics{}, errors.Wrap(err, "failed to create rabbit_consumer_scaled_total metric")
+	}
+
+	if metrics.circuitBreakerState, err = meter.Int64Gauge(
+		getMetricsPrefix(prefix, rabbitCircuitBreakerState),
+		metric.WithDescription("State of the publisher circuit breaker (0 - closed, 1 - half-open, 2 - open)"),
+	); err != nil {
+		return rabbitMetrics{}, errors.Wrap(err, "failed to create rabbit_circuit_breaker_state metric")
+	}
+
+	if metrics.circuitBreakerRejected, err = meter.Int64Counter(
+		getMetricsPrefix(prefix, rabbitCircuitBreakerRejected),
+		metric.WithDescription("Total number of messages rejected by an open circuit breaker"),
+	); err != nil {
+		return rabbitMetrics{}, errors.Wrap(err, "failed to create rabbit_circuit_breaker_rejected_total metric")
+	}
+
 	return
 }
 
@@ -139,3 +257,47 @@ func (m *rabbitMetrics) DecrementConsumers(queueName string, attributes ...attri
 		metric.WithAttributes(attributes...),
 	)
 }
+
+func (m *rabbitMetrics) RecordConcurrencyLimit(queueName string, limit int) {
+	m.consumerConcurrencyLimit.Record(context.Background(), int64(limit),
+		metric.WithAttributes(attribute.String(attrQueueName, queueName)),
+	)
+}
+
+func (m *rabbitMetrics) IncrementConsumerScaled(queueName string, direction string) {
+	m.consumerScaled.Add(context.Background(), 1,
+		metric.WithAttributes(attribute.String(attrQueueName, queueName), attribute.String(attrDirection, direction)),
+	)
+}
+
+func (m *rabbitMetrics) RecordCircuitBreakerState(topic string, state CircuitState) {
+	m.circuitBreakerState.Record(context.Background(), int64(state),
+		metric.WithAttributes(attribute.String(attrTopic, topic)),
+	)
+}
+
+func (m *rabbitMetrics) IncrementCircuitBreakerRejected(topic string) {
+	m.circuitBreakerRejected.Add(context.Background(), 1,
+		metric.WithAttributes(attribute.String(attrTopic, topic)),
+	)
+}
+
+// RecordQueueStats records the queue depth and consumer metrics for the given queue
+func (m *rabbitMetrics) RecordQueueStats(stats QueueStats) {
+	attributes := metric.WithAttributes(attribute.String(attrQueueName, stats.Queue))
+
+	m.queueMessages.Record(context.Background(), int64(stats.Messages), attributes)
+	m.queueConsumers.Record(context.Background(), int64(stats.Consumers), attributes)
+
+	// The rest of the stats are only available through the management API
+	if !stats.Detailed {
+		return
+	}
+
+	m.queueMessagesReady.Record(context.Background(), int64(stats.MessagesReady), attributes)
+	m.queueMessagesUnacked.Record(context.Background(), int64(stats.MessagesUnacknowledged), attributes)
+	m.queuePublishRate.Record(context.Background(), stats.PublishRate, attributes)
+	m.queueDeliverRate.Record(context.Background(), stats.DeliverRate, attributes)
+	m.queueAckRate.Record(context.Background(), stats.AckRate, attributes)
+	m.queueLagSeconds.Record(context.Background(), stats.Lag().Seconds(), attributes)
+}
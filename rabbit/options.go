@@ -1,6 +1,8 @@
 package rabbit
 
 import (
+	"time"
+
 	"github.com/wagslane/go-rabbitmq"
 )
 
@@ -8,12 +10,19 @@ type Options struct {
 	logger         rabbitmq.Logger
 	publishers     int
 	replyConsumers int
+	directReplyTo  bool
+
+	queueStatsInterval time.Duration
+
+	circuitBreaker       *CircuitBreakerConfig
+	topicCircuitBreakers map[Topic]CircuitBreakerConfig
 }
 
 func newRabbitOptions() *Options {
 	return &Options{
-		publishers:     1,
-		replyConsumers: 1,
+		publishers:           1,
+		replyConsumers:       1,
+		topicCircuitBreakers: make(map[Topic]CircuitBreakerConfig),
 	}
 }
 
@@ -36,3 +45,35 @@ func WithConcurrentReplyConsumer(number int) func(options *Options) {
 		options.replyConsumers = number
 	}
 }
+
+// WithDirectReplyTo makes RPC requests use the RabbitMQ direct reply-to pseudo-queue instead of the reply queue,
+// which lowers the latency of the replies. Replies that arrive after the channel is reconnected are lost.
+// The reply queue is not declared, so WithConcurrentReplyConsumer has no effect.
+func WithDirectReplyTo() func(options *Options) {
+	return func(options *Options) {
+		options.directReplyTo = true
+	}
+}
+
+// WithQueueStats enables periodic collection of the depth and consumer count of the consumed queues.
+// If the management API address is configured, message rates are collected as well.
+func WithQueueStats(interval time.Duration) func(options *Options) {
+	return func(options *Options) {
+		options.queueStatsInterval = interval
+	}
+}
+
+// WithCircuitBreaker enables a circuit breaker for every topic the client publishes to.
+// When the circuit of a topic is open, publishing fails fast with a CircuitOpenError.
+func WithCircuitBreaker(config CircuitBreakerConfig) func(options *Options) {
+	return func(options *Options) {
+		options.circuitBreaker = &config
+	}
+}
+
+// WithTopicCircuitBreaker enables a circuit breaker for the given topic, overriding the config set with WithCircuitBreaker
+func WithTopicCircuitBreaker(topic Topic, config CircuitBreakerConfig) func(options *Options) {
+	return func(options *Options) {
+		options.topicCircuitBreakers[topic] = config
+	}
+}
@@ -62,6 +62,43 @@ func TestOptionsWithConcurrentReplyConsumer(t *testing.T) {
 	assert.Equal(t, 2, options.replyConsumers)
 }
 
+func TestOptionsWithDirectReplyTo(t *testing.T) {
+	options := newRabbitOptions()
+
+	assert.False(t, options.directReplyTo)
+
+	WithDirectReplyTo()(options)
+
+	assert.True(t, options.directReplyTo)
+}
+
+func TestConsumerOptionsWithQueueProperties(t *testing.T) {
+	options := newConsumerOptions()
+
+	assert.False(t, options.exclusive)
+	assert.False(t, options.autoDelete)
+
+	WithExclusiveQueue(true)(&options)
+	WithAutoDeleteQueue(true)(&options)
+
+	assert.True(t, options.exclusive)
+	assert.True(t, options.autoDelete)
+}
+
+func TestConsumerOptionsWithRoutineRange(t *testing.T) {
+	options := newConsumerOptions()
+	assert.Equal(t, 0, options.maxRoutines)
+
+	WithRoutineRange(2, 10)(&options)
+	assert.Equal(t, 2, options.minRoutines)
+	assert.Equal(t, 10, options.maxRoutines)
+
+	// The maximum is never lower than the minimum
+	WithRoutineRange(0, 0)(&options)
+	assert.Equal(t, 1, options.minRoutines)
+	assert.Equal(t, 1, options.maxRoutines)
+}
+
 func TestPublisherOptionsWithHeader(t *testing.T) {
 	publisherOpts := newPublisherOptions()
 
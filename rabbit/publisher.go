@@ -3,6 +3,7 @@ package rabbit
 import (
 	"context"
 
+	"github.com/pkg/errors"
 	"github.com/wagslane/go-rabbitmq"
 	"github.com/xBlaz3kx/DevX/observability"
 	"go.opentelemetry.io/otel/trace"
@@ -10,6 +11,11 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+var (
+	ErrNotConfirmed    = errors.New("message was not confirmed by the broker")
+	ErrNotAcknowledged = errors.New("message was negatively acknowledged by the broker")
+)
+
 type Publisher struct {
 	Publisher *rabbitmq.Publisher
 	obs       observability.Observability
@@ -27,32 +33,14 @@ func newPublisher(publisher *rabbitmq.Publisher, metrics rabbitMetrics, obs obse
 func (pb *Publisher) Publish(ctx context.Context, topic string, message proto.Message, correlationID string, replyTopic Topic, optionFuncs ...PublishOpt) error {
 	logger := pb.obs.Log().Ctx(ctx).With(zap.String("topic", topic), zap.String("correlationId", correlationID))
 
-	// Apply options
-	publisherOptions := newPublisherOptions()
-	for _, optionFunc := range optionFuncs {
-		optionFunc(publisherOptions)
-	}
-
-	// Get the headers
-	headers := getPublisherHeaders(ctx, publisherOptions)
-
-	// Marshall the payload
-	payload, err := proto.Marshal(message)
+	publishing, err := newPublishing(ctx, topic, message, correlationID, replyTopic, optionFuncs...)
 	if err != nil {
 		logger.Error("Error marshalling message", zap.Error(err))
 		return err
 	}
 
 	// Publish the message
-	err = pb.Publisher.Publish(
-		payload,
-		[]string{topic},
-		rabbitmq.WithPublishOptionsExchange(string(CentralExchange)),
-		rabbitmq.WithPublishOptionsContentType("application/vnd.google.protobuf"),
-		rabbitmq.WithPublishOptionsCorrelationID(correlationID),
-		rabbitmq.WithPublishOptionsHeaders(headers),
-		rabbitmq.WithPublishOptionsReplyTo(string(replyTopic)),
-	)
+	err = pb.Publisher.Publish(publishing.payload, []string{topic}, publishing.options...)
 	if err != nil {
 		logger.Error("Error publishing a message", zap.Error(err))
 		return err
@@ -61,11 +49,108 @@ func (pb *Publisher) Publish(ctx context.Context, topic string, message proto.Me
 	// Increment the number of messages published
 	pb.metrics.IncrementMessagesPublished(topic)
 
-	logger.With(zap.Any("headers", headers)).Debug("Published message")
+	logger.With(zap.Any("headers", publishing.headers)).Debug("Published message")
 
 	return nil
 }
 
+// PublishBatch publishes all the messages on the publisher channel and waits for the broker confirmations at once.
+// The publisher must be in confirm mode. Returns an error for each request, nil if the message was confirmed.
+func (pb *Publisher) PublishBatch(ctx context.Context, requests []PublishRequest, replyTopic Topic) []error {
+	results := make([]error, len(requests))
+	confirmations := make([]rabbitmq.PublisherConfirmation, len(requests))
+
+	for i, request := range requests {
+		publishing, err := newPublishing(ctx, string(request.Topic), request.Message, request.CorrelationId, replyTopic, request.Options...)
+		if err != nil {
+			results[i] = err
+			continue
+		}
+
+		confirmations[i], results[i] = pb.Publisher.PublishWithDeferredConfirmWithContext(ctx, publishing.payload, []string{string(request.Topic)}, publishing.options...)
+	}
+
+	// Wait for the confirmations of all published messages
+	for i, confirmation := range confirmations {
+		if results[i] != nil {
+			continue
+		}
+
+		results[i] = waitConfirmation(ctx, confirmation)
+		if results[i] == nil {
+			pb.metrics.IncrementMessagesPublished(string(requests[i].Topic))
+		}
+	}
+
+	return results
+}
+
+// waitConfirmation waits until the broker acknowledges the message
+func waitConfirmation(ctx context.Context, confirmation rabbitmq.PublisherConfirmation) error {
+	if len(confirmation) == 0 {
+		return ErrNotConfirmed
+	}
+
+	for _, deferred := range confirmation {
+		if deferred == nil {
+			return ErrNotConfirmed
+		}
+
+		acked, err := deferred.WaitContext(ctx)
+		if err != nil {
+			return err
+		}
+
+		if !acked {
+			return ErrNotAcknowledged
+		}
+	}
+
+	return nil
+}
+
+type publishing struct {
+	payload []byte
+	headers rabbitmq.Table
+	options []func(*rabbitmq.PublishOptions)
+}
+
+// newPublishing marshals the message and creates the publishing options for it
+func newPublishing(ctx context.Context, topic string, message proto.Message, correlationID string, replyTopic Topic, optionFuncs ...PublishOpt) (*publishing, error) {
+	// Apply options
+	publisherOptions := newPublisherOptions()
+	for _, optionFunc := range optionFuncs {
+		optionFunc(publisherOptions)
+	}
+
+	// Get the headers
+	headers := getPublisherHeaders(ctx, publisherOptions)
+
+	// Marshall the payload
+	payload, err := proto.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replies to direct reply-to consumers must be published to the default exchange
+	exchange := string(CentralExchange)
+	if isDirectReplyTopic(topic) {
+		exchange = ""
+	}
+
+	return &publishing{
+		payload: payload,
+		headers: headers,
+		options: []func(*rabbitmq.PublishOptions){
+			rabbitmq.WithPublishOptionsExchange(exchange),
+			rabbitmq.WithPublishOptionsContentType("application/vnd.google.protobuf"),
+			rabbitmq.WithPublishOptionsCorrelationID(correlationID),
+			rabbitmq.WithPublishOptionsHeaders(headers),
+			rabbitmq.WithPublishOptionsReplyTo(string(replyTopic)),
+		},
+	}, nil
+}
+
 func getPublisherHeaders(ctx context.Context, publisherOptions *PublisherOptions) rabbitmq.Table {
 	headers := make(rabbitmq.Table)
 	for _, hv := range publisherOptions.headers {
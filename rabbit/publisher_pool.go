@@ -2,8 +2,10 @@ package rabbit
 
 import (
 	"context"
+	"sync"
 
 	"github.com/google/uuid"
+	"github.com/pkg/errors"
 	"github.com/xBlaz3kx/DevX/observability"
 	grpc "github.com/xBlaz3kx/DevX/proto"
 	"go.opentelemetry.io/otel/trace"
@@ -19,6 +21,50 @@ type PublisherPool struct {
 	exchange   Exchange
 	replyTopic Topic
 	obs        observability.Observability
+
+	// directReply is set when RPC requests should use direct reply-to instead of the reply queue
+	directReply *directReplyChannel
+
+	// batchPublisher is a publisher in confirm mode, used for publishing batches
+	batchPublisher *batchPublisher
+
+	// breakers are the per-topic circuit breakers, nil if disabled
+	breakers *circuitBreakers
+	metrics  rabbitMetrics
+
+	// catalog keeps the declared publications
+	catalog *catalog
+}
+
+// batchPublisher creates the publisher in confirm mode on the first batch, so clients without batches don't open its channel
+type batchPublisher struct {
+	mu        sync.Mutex
+	create    func() (*Publisher, error)
+	publisher *Publisher
+}
+
+// get returns the publisher, creating it if needed. Creating is retried on the next batch if it fails.
+func (b *batchPublisher) get() (*Publisher, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.publisher == nil {
+		publisher, err := b.create()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create batch publisher")
+		}
+
+		b.publisher = publisher
+	}
+
+	return b.publisher, nil
+}
+
+// PublishResult is the outcome of publishing a single message of a batch
+type PublishResult struct {
+	CorrelationId string
+	Topic         Topic
+	Err           error
 }
 
 type PublishRequest struct {
@@ -32,15 +78,20 @@ type PublishRequest struct {
 
 // NewPublisherPool creates a new publisher pool that handles all publishing for the service
 // It is routine-safe
-func newPublisherPool(publishers []Publisher, replyPool ReplyPool, exchange Exchange, replyTopic Topic, obs observability.Observability) PublisherPool {
+func newPublisherPool(publishers []Publisher, createBatchPublisher func() (*Publisher, error), replyPool ReplyPool, exchange Exchange, replyTopic Topic, directReply *directReplyChannel, breakers *circuitBreakers, catalog *catalog, metrics rabbitMetrics, obs observability.Observability) PublisherPool {
 	publisherPool := PublisherPool{
-		publishers: publishers,
-		request:    make(chan *PublishRequest, 30),
-		roundRobin: 0,
-		replyPool:  replyPool,
-		exchange:   exchange,
-		replyTopic: replyTopic,
-		obs:        obs.WithSpanKind(trace.SpanKindProducer),
+		publishers:     publishers,
+		batchPublisher: &batchPublisher{create: createBatchPublisher},
+		request:        make(chan *PublishRequest, 30),
+		roundRobin:     0,
+		replyPool:      replyPool,
+		exchange:       exchange,
+		replyTopic:     replyTopic,
+		directReply:    directReply,
+		breakers:       breakers,
+		catalog:        catalog,
+		metrics:        metrics,
+		obs:            obs.WithSpanKind(trace.SpanKindProducer),
 	}
 	return publisherPool
 }
@@ -57,9 +108,24 @@ func (pp *PublisherPool) start() {
 	}
 }
 
+// DeclarePublication declares that the service publishes messages of the given type to the topic.
+// Declared publications are described in the AsyncAPI document.
+func (pp *PublisherPool) DeclarePublication(topic Topic, message proto.Message) {
+	pp.catalog.addPublication(PublicationInfo{
+		Exchange: CentralExchange,
+		Topic:    topic,
+		Message:  message,
+	})
+}
+
 // Publish publishes a rabbit message
 // Returns an error, only initialize it if needed, error already logged
 func (pp *PublisherPool) Publish(ctx context.Context, topic Topic, message proto.Message, options ...PublishOpt) error {
+	permit, err := pp.allow(topic)
+	if err != nil {
+		return err
+	}
+
 	correlationId := uuid.New().String()
 	// Create a channel for the response and close it when done
 	errChan := make(chan error, 1)
@@ -76,7 +142,8 @@ func (pp *PublisherPool) Publish(ctx context.Context, topic Topic, message proto
 
 	pp.request <- publishRequest
 
-	err := waitError(ctx, publishRequest.ResponseChannel)
+	err = waitError(ctx, publishRequest.ResponseChannel)
+	permit.record(err == nil)
 	if err != nil {
 		pp.obs.Log().Error(
 			"Unable to publish rabbit message",
@@ -88,6 +155,73 @@ func (pp *PublisherPool) Publish(ctx context.Context, topic Topic, message proto
 	return err
 }
 
+// PublishBatch publishes the messages on a single channel and waits for the broker confirmations once,
+// instead of going through the publisher routine for each message. Only the Topic, Message, CorrelationId and Options
+// of the requests are used. Returns a result for each request, in the same order.
+func (pp *PublisherPool) PublishBatch(ctx context.Context, requests []PublishRequest) []PublishResult {
+	results := make([]PublishResult, len(requests))
+	permits := make([]circuitPermit, len(requests))
+	batch := make([]PublishRequest, 0, len(requests))
+	batchIndexes := make([]int, 0, len(requests))
+
+	for i, request := range requests {
+		if request.CorrelationId == "" {
+			request.CorrelationId = uuid.New().String()
+		}
+
+		results[i] = PublishResult{CorrelationId: request.CorrelationId, Topic: request.Topic}
+
+		permits[i], results[i].Err = pp.allow(request.Topic)
+		if results[i].Err != nil {
+			continue
+		}
+
+		batch = append(batch, request)
+		batchIndexes = append(batchIndexes, i)
+	}
+
+	if len(batch) == 0 {
+		return results
+	}
+
+	errs := pp.publishBatch(ctx, batch)
+	failed := 0
+	for j, err := range errs {
+		i := batchIndexes[j]
+		results[i].Err = err
+		permits[i].record(err == nil)
+
+		if err != nil {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		pp.obs.Log().Error(
+			"Unable to publish some messages of a batch",
+			zap.Int("failed", failed),
+			zap.Int("total", len(requests)),
+		)
+	}
+
+	return results
+}
+
+// publishBatch publishes the batch with the batch publisher, failing every message if it cannot be created
+func (pp *PublisherPool) publishBatch(ctx context.Context, batch []PublishRequest) []error {
+	publisher, err := pp.batchPublisher.get()
+	if err != nil {
+		errs := make([]error, len(batch))
+		for i := range errs {
+			errs[i] = err
+		}
+
+		return errs
+	}
+
+	return publisher.PublishBatch(ctx, batch, pp.replyTopic)
+}
+
 // Respond publishes a response to a rabbit message
 // Set isError to true if the reply is an error, otherwise pass false to indicate valid response
 // Returns an error, only initialize it if necessary
@@ -138,17 +272,63 @@ func (pp *PublisherPool) respond(ctx context.Context, correlationID string, topi
 	return err
 }
 
-// PublishRPC publishes a RPC message and waits for the reply
+// PublishRPC publishes a RPC message and waits for the reply.
+// If the responder replied with an error, the error reply payload is returned with a *RPCError, which matches ErrResponse.
 func (pp *PublisherPool) PublishRPC(ctx context.Context, topic Topic, message proto.Message, options ...PublishOpt) ([]byte, error) {
-	replyChannel, err := pp.PublishRPCWithMultipleResponses(ctx, topic, message, 1, options...)
+	permit, err := pp.allow(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	replyChannel, err := pp.publishRPC(ctx, topic, message, 1, options...)
 	if err != nil {
+		permit.record(false)
 		return nil, err
 	}
 
-	return waitReply(ctx, replyChannel)
+	reply, err := waitReply(ctx, replyChannel)
+
+	// An error reply means the responder is alive
+	permit.record(err == nil || errors.Is(err, ErrResponse))
+
+	return reply, err
 }
 
+// PublishRPCWithMultipleResponses publishes a RPC message and returns a channel for the replies.
+// Only publishing failures are recorded by the circuit breaker, as the replies are not awaited.
 func (pp *PublisherPool) PublishRPCWithMultipleResponses(ctx context.Context, topic Topic, message proto.Message, nrResponses int, options ...PublishOpt) (chan ReplyResponse, error) {
+	permit, err := pp.allow(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	replyChannel, err := pp.publishRPC(ctx, topic, message, nrResponses, options...)
+	permit.record(err == nil)
+
+	return replyChannel, err
+}
+
+// allow checks the circuit breaker of the topic. Returns an empty permit if the topic has no circuit breaker.
+func (pp *PublisherPool) allow(topic Topic) (circuitPermit, error) {
+	if pp.breakers == nil {
+		return circuitPermit{}, nil
+	}
+
+	breaker := pp.breakers.get(topic)
+	if breaker == nil {
+		return circuitPermit{}, nil
+	}
+
+	permit, err := breaker.allow()
+	if err != nil {
+		pp.metrics.IncrementCircuitBreakerRejected(string(topic))
+		return circuitPermit{}, err
+	}
+
+	return permit, nil
+}
+
+func (pp *PublisherPool) publishRPC(ctx context.Context, topic Topic, message proto.Message, nrResponses int, options ...PublishOpt) (chan ReplyResponse, error) {
 	correlationId := uuid.New().String()
 	// Create a channel for the response and close it when done
 	errChan := make(chan error, 1)
@@ -175,9 +355,14 @@ func (pp *PublisherPool) PublishRPCWithMultipleResponses(ctx context.Context, to
 
 	pp.replyPool.Request <- replyRequest
 
-	pp.request <- publishRequest
+	var err error
+	if pp.directReply != nil {
+		err = pp.directReply.publish(ctx, topic, message, correlationId, options...)
+	} else {
+		pp.request <- publishRequest
+		err = waitError(ctx, publishRequest.ResponseChannel)
+	}
 
-	err := waitError(ctx, publishRequest.ResponseChannel)
 	if err != nil {
 		pp.obs.Log().With(
 			zap.String("topic", string(topic)),
@@ -208,7 +393,7 @@ func waitReply(ctx context.Context, replyChannel chan ReplyResponse) ([]byte, er
 		select {
 		case d := <-replyChannel:
 			if d.Error {
-				return d.Body, ErrResponse
+				return d.Body, d.Err()
 			}
 
 			return d.Body, nil
@@ -0,0 +1,141 @@
+package rabbit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wagslane/go-rabbitmq"
+	"github.com/xBlaz3kx/DevX/observability"
+	grpc "github.com/xBlaz3kx/DevX/proto"
+	tests "github.com/xBlaz3kx/DevX/test_containers"
+)
+
+func TestWaitConfirmation(t *testing.T) {
+	err := waitConfirmation(context.Background(), nil)
+	assert.ErrorIs(t, err, ErrNotConfirmed)
+
+	err = waitConfirmation(context.Background(), rabbitmq.PublisherConfirmation{nil})
+	assert.ErrorIs(t, err, ErrNotConfirmed)
+}
+
+func TestNewPublishing(t *testing.T) {
+	message := NewError("error", grpc.ErrorCode_ApplicationError)
+
+	publishing, err := newPublishing(context.Background(), "service.topic", message, "correlationId", "service.REPLY.host", WithPublisherHeader(NewHeader().WithMethod("method").Build()))
+	require.NoError(t, err)
+	assert.NotEmpty(t, publishing.payload)
+	assert.Equal(t, "method", publishing.headers[string(HeaderKeyMethod)])
+
+	options := &rabbitmq.PublishOptions{}
+	for _, option := range publishing.options {
+		option(options)
+	}
+	assert.Equal(t, string(CentralExchange), options.Exchange)
+	assert.Equal(t, "correlationId", options.CorrelationID)
+	assert.Equal(t, "service.REPLY.host", options.ReplyTo)
+
+	// Direct reply-to replies are published to the default exchange
+	publishing, err = newPublishing(context.Background(), directReplyQueue+".abc", message, "correlationId", "")
+	require.NoError(t, err)
+
+	options = &rabbitmq.PublishOptions{}
+	for _, option := range publishing.options {
+		option(options)
+	}
+	assert.Equal(t, "", options.Exchange)
+}
+
+func TestBatchPublisher_Get(t *testing.T) {
+	created := 0
+	publisher := &batchPublisher{create: func() (*Publisher, error) {
+		created++
+		if created == 1 {
+			return nil, errors.New("channel closed")
+		}
+
+		return &Publisher{}, nil
+	}}
+
+	// The publisher is created on first use and creating it is retried after a failure
+	_, err := publisher.get()
+	assert.ErrorContains(t, err, "channel closed")
+
+	first, err := publisher.get()
+	require.NoError(t, err)
+	second, err := publisher.get()
+	require.NoError(t, err)
+
+	assert.Same(t, first, second)
+	assert.Equal(t, 2, created)
+}
+
+func newBenchmarkRabbit(b *testing.B) *Rabbit {
+	if testing.Short() {
+		b.Skip("Skipping benchmark that requires a RabbitMQ container")
+	}
+
+	ctx := context.Background()
+	container, err := tests.NewRabbitMQContainer(ctx)
+	require.NoError(b, err)
+	b.Cleanup(func() {
+		_ = container.Terminate(ctx)
+	})
+
+	require.NoError(b, container.DeclareExchanges(ctx, string(CentralExchange)))
+
+	url, err := container.AmqpURL(ctx)
+	require.NoError(b, err)
+
+	client, err := New(Configuration{URL: url}, CentralExchange, observability.NewNoopObservability(), WithLogger(&exampleLogger{}))
+	require.NoError(b, err)
+	b.Cleanup(func() {
+		_ = client.Disconnect()
+	})
+
+	return client
+}
+
+func BenchmarkPublisherPool_Publish(b *testing.B) {
+	client := newBenchmarkRabbit(b)
+	topic := NewTopic(CentralExchange).AddWord("benchmark").Build()
+	message := NewError("benchmark", grpc.ErrorCode_ApplicationError)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := client.Publisher.Publish(context.Background(), topic, message)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkPublisherPool_PublishBatch waits for the broker confirmations, unlike BenchmarkPublisherPool_Publish.
+// A batch of a single message is the confirmed single message baseline.
+func BenchmarkPublisherPool_PublishBatch(b *testing.B) {
+	client := newBenchmarkRabbit(b)
+	topic := NewTopic(CentralExchange).AddWord("benchmark").Build()
+	message := NewError("benchmark", grpc.ErrorCode_ApplicationError)
+
+	for _, batchSize := range []int{1, 10, 100, 1000} {
+		b.Run(fmt.Sprintf("batch_%d", batchSize), func(b *testing.B) {
+			batch := make([]PublishRequest, batchSize)
+			for i := range batch {
+				batch[i] = PublishRequest{Topic: topic, Message: message}
+			}
+
+			b.ResetTimer()
+			// Publish the same number of messages as the single message benchmark
+			for published := 0; published < b.N; published += batchSize {
+				for _, result := range client.Publisher.PublishBatch(context.Background(), batch[:min(batchSize, b.N-published)]) {
+					if result.Err != nil {
+						b.Fatal(result.Err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package rabbit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GLCharge/otelzap"
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+// QueueStats is a snapshot of the state of a queue consumed by this client
+type QueueStats struct {
+	Queue     string
+	Messages  int
+	Consumers int
+
+	// Detailed is set when the stats below were obtained from the management API
+	Detailed               bool
+	MessagesReady          int
+	MessagesUnacknowledged int
+	PublishRate            float64
+	DeliverRate            float64
+	AckRate                float64
+}
+
+// Lag estimates how long it would take the consumers to drain the current backlog at the observed rates
+func (s QueueStats) Lag() time.Duration {
+	rate := s.AckRate
+	if rate <= 0 {
+		rate = s.DeliverRate
+	}
+
+	if rate <= 0 || s.Messages == 0 {
+		return 0
+	}
+
+	return time.Duration(float64(s.Messages) / rate * float64(time.Second))
+}
+
+// queueRegistry keeps track of the queues consumed by the client. It is shared between the copies of the ConsumerFactory.
+type queueRegistry struct {
+	mu     sync.RWMutex
+	queues map[string]struct{}
+}
+
+func newQueueRegistry() *queueRegistry {
+	return &queueRegistry{queues: make(map[string]struct{})}
+}
+
+func (r *queueRegistry) add(queueName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queues[queueName] = struct{}{}
+}
+
+func (r *queueRegistry) list() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	queues := make([]string, 0, len(r.queues))
+	for queue := range r.queues {
+		queues = append(queues, queue)
+	}
+
+	return queues
+}
+
+// queueStatsCollector periodically passive-declares the consumed queues and optionally queries the management API
+type queueStatsCollector struct {
+	amqpURL    string
+	interval   time.Duration
+	queues     *queueRegistry
+	management *managementClient
+	metrics    rabbitMetrics
+	logger     *otelzap.Logger
+
+	conn    *amqp.Connection
+	channel *amqp.Channel
+
+	mu    sync.RWMutex
+	stats map[string]QueueStats
+}
+
+func newQueueStatsCollector(amqpURL string, managementURL string, interval time.Duration, queues *queueRegistry, metrics rabbitMetrics, logger *otelzap.Logger) (*queueStatsCollector, error) {
+	collector := &queueStatsCollector{
+		amqpURL:  amqpURL,
+		interval: interval,
+		queues:   queues,
+		metrics:  metrics,
+		logger:   logger,
+		stats:    make(map[string]QueueStats),
+	}
+
+	if managementURL != "" {
+		management, err := newManagementClient(managementURL, amqpURL)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create management API client")
+		}
+		collector.management = management
+	}
+
+	return collector, nil
+}
+
+// start collects the queue stats every interval until the context is cancelled
+func (qc *queueStatsCollector) start(ctx context.Context) {
+	ticker := time.NewTicker(qc.interval)
+	defer ticker.Stop()
+	defer qc.close()
+
+	for {
+		select {
+		case <-ticker.C:
+			qc.collect(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (qc *queueStatsCollector) collect(ctx context.Context) {
+	for _, queue := range qc.queues.list() {
+		stats, err := qc.declarePassive(queue)
+		if err != nil {
+			qc.logger.Warn("Unable to passive declare queue", zap.String("queueName", queue), zap.Error(err))
+			continue
+		}
+
+		if qc.management != nil {
+			details, err := qc.management.queue(ctx, queue)
+			if err != nil {
+				qc.logger.Warn("Unable to get queue details from the management API", zap.String("queueName", queue), zap.Error(err))
+			} else {
+				stats = details
+			}
+		}
+
+		qc.metrics.RecordQueueStats(stats)
+
+		qc.mu.Lock()
+		qc.stats[queue] = stats
+		qc.mu.Unlock()
+	}
+}
+
+// get returns the last collected stats for the queue
+func (qc *queueStatsCollector) get(queue string) (QueueStats, bool) {
+	qc.mu.RLock()
+	defer qc.mu.RUnlock()
+
+	stats, ok := qc.stats[queue]
+	return stats, ok
+}
+
+// declarePassive reads the message and consumer counts of a queue without modifying it
+func (qc *queueStatsCollector) declarePassive(queue string) (QueueStats, error) {
+	if err := qc.ensureChannel(); err != nil {
+		return QueueStats{}, err
+	}
+
+	q, err := qc.channel.QueueDeclarePassive(queue, false, false, false, false, nil)
+	if err != nil {
+		// The server closes the channel when the passive declare fails
+		qc.channel = nil
+		return QueueStats{}, err
+	}
+
+	return QueueStats{
+		Queue:     queue,
+		Messages:  q.Messages,
+		Consumers: q.Consumers,
+	}, nil
+}
+
+func (qc *queueStatsCollector) ensureChannel() error {
+	if qc.conn == nil || qc.conn.IsClosed() {
+		conn, err := amqp.Dial(qc.amqpURL)
+		if err != nil {
+			return errors.Wrap(err, "failed to create a rabbit connection")
+		}
+
+		qc.conn = conn
+		qc.channel = nil
+	}
+
+	if qc.channel == nil || qc.channel.IsClosed() {
+		channel, err := qc.conn.Channel()
+		if err != nil {
+			return errors.Wrap(err, "failed to open a channel")
+		}
+
+		qc.channel = channel
+	}
+
+	return nil
+}
+
+func (qc *queueStatsCollector) close() {
+	if qc.conn != nil && !qc.conn.IsClosed() {
+		_ = qc.conn.Close()
+	}
+}
+
+// managementClient queries the RabbitMQ management HTTP API
+type managementClient struct {
+	baseURL    *url.URL
+	vhost      string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+type managementRate struct {
+	Rate float64 `json:"rate"`
+}
+
+type managementQueue struct {
+	Name                   string `json:"name"`
+	Messages               int    `json:"messages"`
+	MessagesReady          int    `json:"messages_ready"`
+	MessagesUnacknowledged int    `json:"messages_unacknowledged"`
+	Consumers              int    `json:"consumers"`
+	MessageStats           struct {
+		PublishDetails    managementRate `json:"publish_details"`
+		DeliverGetDetails managementRate `json:"deliver_get_details"`
+		AckDetails        managementRate `json:"ack_details"`
+	} `json:"message_stats"`
+}
+
+// newManagementClient creates a management API client. Credentials and the vhost are taken from the AMQP URL,
+// unless the management URL contains credentials.
+func newManagementClient(managementURL string, amqpURL string) (*managementClient, error) {
+	baseURL, err := url.Parse(managementURL)
+	if err != nil {
+		return nil, err
+	}
+
+	amqpUri, err := amqp.ParseURI(amqpURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &managementClient{
+		vhost:      amqpUri.Vhost,
+		username:   amqpUri.Username,
+		password:   amqpUri.Password,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+
+	if baseURL.User != nil {
+		client.username = baseURL.User.Username()
+		client.password, _ = baseURL.User.Password()
+		baseURL.User = nil
+	}
+
+	client.baseURL = baseURL
+	return client, nil
+}
+
+func (mc *managementClient) queue(ctx context.Context, queue string) (QueueStats, error) {
+	endpoint := fmt.Sprintf("%s/api/queues/%s/%s",
+		strings.TrimSuffix(mc.baseURL.String(), "/"),
+		url.PathEscape(mc.vhost),
+		url.PathEscape(queue),
+	)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return QueueStats{}, err
+	}
+	request.SetBasicAuth(mc.username, mc.password)
+
+	response, err := mc.httpClient.Do(request)
+	if err != nil {
+		return QueueStats{}, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return QueueStats{}, fmt.Errorf("management API responded with status %d", response.StatusCode)
+	}
+
+	var details managementQueue
+	if err := json.NewDecoder(response.Body).Decode(&details); err != nil {
+		return QueueStats{}, errors.Wrap(err, "failed to decode management API response")
+	}
+
+	return QueueStats{
+		Queue:                  queue,
+		Messages:               details.Messages,
+		Consumers:              details.Consumers,
+		Detailed:               true,
+		MessagesReady:          details.MessagesReady,
+		MessagesUnacknowledged: details.MessagesUnacknowledged,
+		PublishRate:            details.MessageStats.PublishDetails.Rate,
+		DeliverRate:            details.MessageStats.DeliverGetDetails.Rate,
+		AckRate:                details.MessageStats.AckDetails.Rate,
+	}, nil
+}
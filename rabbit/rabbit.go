@@ -1,9 +1,12 @@
 package rabbit
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/wagslane/go-rabbitmq"
 	"github.com/xBlaz3kx/DevX/observability"
@@ -17,6 +20,10 @@ var ErrResponse = errors.New("responded with an error")
 type Configuration struct {
 	// URL is the address for connecting to the RabbitMQ instance
 	URL string `json:"address" yaml:"address" validate:"required"`
+
+	// ManagementURL is the optional address of the RabbitMQ management HTTP API, used for detailed queue metrics.
+	// If it does not contain credentials, the credentials from the URL are used.
+	ManagementURL string `json:"managementAddress,omitempty" yaml:"managementAddress"`
 }
 
 type Rabbit struct {
@@ -31,6 +38,9 @@ type Rabbit struct {
 	replyTopic       Topic
 	obs              observability.Observability
 	metrics          rabbitMetrics
+	directReply      *directReplyChannel
+	queueStats       *queueStatsCollector
+	stopQueueStats   context.CancelFunc
 }
 
 func NewError(errorMessage string, errorCode grpc.ErrorCode) *grpc.Error {
@@ -42,12 +52,11 @@ func NewError(errorMessage string, errorCode grpc.ErrorCode) *grpc.Error {
 
 // New creates and returns a new rabbit client with given configuration
 func New(configuration Configuration, serviceExchange Exchange, obs observability.Observability, opts ...func(*Options)) (*Rabbit, error) {
-	// Create reply topic
-	instanceHostname, err := os.Hostname()
+	// Create a reply topic, unique to this instance
+	replyTopic, err := newReplyTopic(serviceExchange)
 	if err != nil {
 		return nil, err
 	}
-	replyTopic := NewTopic(serviceExchange).AddWord(replyBase).AddWord(TopicWord(instanceHostname)).Build()
 
 	// Apply options
 	options := newRabbitOptions()
@@ -60,6 +69,7 @@ func New(configuration Configuration, serviceExchange Exchange, obs observabilit
 		zap.String("replyTopic", string(replyTopic)),
 		zap.Int("replyConsumers", options.replyConsumers),
 		zap.Int("publishers", options.publishers),
+		zap.Bool("directReplyTo", options.directReplyTo),
 	)
 	logger.Debug("Starting Rabbitmq")
 
@@ -91,10 +101,17 @@ func New(configuration Configuration, serviceExchange Exchange, obs observabilit
 	client.replyPool = NewReplyPool(30)
 	go client.replyPool.start()
 
-	// Start a reply consumer
-	_, err = newReplyConsumer(client.ConsumerFactory, client.replyPool.Response, replyTopic)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to create reply consumer")
+	// Use a dedicated channel for the RPC requests with direct reply-to, otherwise consume the reply queue
+	if options.directReplyTo {
+		client.directReply, err = newDirectReplyChannel(configuration.URL, client.replyPool.Response, metrics, obs)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create direct reply-to channel")
+		}
+	} else {
+		_, err = newReplyConsumer(client.ConsumerFactory, client.replyPool.Response, replyTopic, options.replyConsumers)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create reply consumer")
+		}
 	}
 
 	// Start a publisher pool
@@ -103,14 +120,53 @@ func New(configuration Configuration, serviceExchange Exchange, obs observabilit
 		return nil, errors.Wrap(err, "failed to create publishers")
 	}
 
-	client.Publisher = newPublisherPool(poolPublishers, client.replyPool, serviceExchange, replyTopic, obs)
+	client.Publisher = newPublisherPool(
+		poolPublishers,
+		client.createBatchPublisher,
+		client.replyPool,
+		serviceExchange,
+		replyTopic,
+		client.directReply,
+		client.createCircuitBreakers(),
+		client.ConsumerFactory.catalog,
+		metrics,
+		obs,
+	)
 	go client.Publisher.start()
 
+	// Start collecting the queue stats
+	if options.queueStatsInterval > 0 {
+		err = client.startQueueStats(options.queueStatsInterval)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to start queue stats collector")
+		}
+	}
+
 	logger.Info("Rabbit service started")
 
 	return client, nil
 }
 
+// newReplyTopic creates a reply topic unique to the process, so multiple instances on the same host don't share replies
+func newReplyTopic(serviceExchange Exchange) (Topic, error) {
+	instanceId, err := newInstanceId()
+	if err != nil {
+		return "", err
+	}
+
+	return NewTopic(serviceExchange).AddWord(replyBase).AddWord(instanceId).Build(), nil
+}
+
+// newInstanceId creates a unique identifier from the hostname
+func newInstanceId() (TopicWord, error) {
+	instanceHostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+
+	return TopicWord(fmt.Sprintf("%s-%s", instanceHostname, uuid.NewString())), nil
+}
+
 // Connect connects the rabbit client to rabbitmq server
 func (c *Rabbit) createConnection() (*rabbitmq.Conn, error) {
 	c.obs.Log().With(zap.String("address", c.configuration.URL)).Debug("Creating a rabbit connection")
@@ -128,6 +184,17 @@ func (c *Rabbit) createConnection() (*rabbitmq.Conn, error) {
 func (c *Rabbit) Disconnect() error {
 	c.obs.Log().Debug("Disconnecting all rabbit connections")
 
+	if c.stopQueueStats != nil {
+		c.stopQueueStats()
+	}
+
+	if c.directReply != nil {
+		err := c.directReply.close()
+		if err != nil {
+			return err
+		}
+	}
+
 	for _, c := range c.connections {
 		err := c.Close()
 		if err != nil {
@@ -160,6 +227,26 @@ func (c *Rabbit) createPublishers(number int) ([]Publisher, error) {
 	return publishers, nil
 }
 
+// createBatchPublisher creates a publisher in confirm mode for publishing batches
+func (c *Rabbit) createBatchPublisher() (*Publisher, error) {
+	err := c.createConnectionIfDoesntExist()
+	if err != nil {
+		return nil, err
+	}
+
+	publisher, err := rabbitmq.NewPublisher(
+		c.connections[0],
+		rabbitmq.WithPublisherOptionsLogger(c.options.logger),
+		rabbitmq.WithPublisherOptionsConfirm,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	batchPublisher := newPublisher(publisher, c.metrics, c.obs)
+	return &batchPublisher, nil
+}
+
 func (c *Rabbit) createConnectionIfDoesntExist() error {
 	if len(c.connections) == 0 {
 		// Create a connection if there is none
@@ -172,6 +259,48 @@ func (c *Rabbit) createConnectionIfDoesntExist() error {
 	return nil
 }
 
+// startQueueStats starts a background routine that periodically collects the stats of the consumed queues
+func (c *Rabbit) startQueueStats(interval time.Duration) error {
+	collector, err := newQueueStatsCollector(
+		c.configuration.URL,
+		c.configuration.ManagementURL,
+		interval,
+		c.ConsumerFactory.queues,
+		c.metrics,
+		c.obs.Log(),
+	)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.queueStats = collector
+	c.stopQueueStats = cancel
+	c.ConsumerFactory.queueStats = collector
+
+	go collector.start(ctx)
+	return nil
+}
+
+// createCircuitBreakers creates the publisher circuit breakers if any are configured
+func (c *Rabbit) createCircuitBreakers() *circuitBreakers {
+	if c.options.circuitBreaker == nil && len(c.options.topicCircuitBreakers) == 0 {
+		return nil
+	}
+
+	return newCircuitBreakers(c.options.circuitBreaker, c.options.topicCircuitBreakers, c.metrics, c.obs.Log())
+}
+
+// QueueStats returns the last collected stats of a consumed queue.
+// Returns false if the stats were not collected (yet) or the queue stats collection is disabled.
+func (c *Rabbit) QueueStats(queueName string) (QueueStats, bool) {
+	if c.queueStats == nil {
+		return QueueStats{}, false
+	}
+
+	return c.queueStats.get(queueName)
+}
+
 func (c *Rabbit) Pass() bool {
 	// todo need to modify the underlying library to expose IsClosed attribute
 	return true
@@ -36,6 +36,15 @@ type ReplyResponse struct {
 	Headers       map[string]any
 }
 
+// Err returns the *RPCError of an error reply, nil otherwise
+func (r ReplyResponse) Err() error {
+	if !r.Error {
+		return nil
+	}
+
+	return ErrorFromReply(r.Body)
+}
+
 // NewReplyPool creates and returns a new ReplyPool
 func NewReplyPool(bufferSize int) ReplyPool {
 	return ReplyPool{
@@ -73,16 +82,27 @@ func (rp *ReplyPool) start() {
 }
 
 // NewReplyConsumer creates a new reply queue consumer
-func newReplyConsumer(consumer ConsumerFactory, responseChannel chan<- ReplyResponse, topic Topic) (*rabbitmq.Consumer, error) {
+// The reply queue is exclusive and auto-deleted, as it is made per-instance
+func newReplyConsumer(consumer ConsumerFactory, responseChannel chan<- ReplyResponse, topic Topic, routines int) (*rabbitmq.Consumer, error) {
 	return consumer.NewConsumer(consumer.exchange, topic, string(topic), func(ctx context.Context, d rabbitmq.Delivery) (action rabbitmq.Action) {
 		response := ReplyResponse{
 			CorrelationId: d.CorrelationId,
 			Body:          d.Body,
-			Error:         d.Headers["error"].(bool),
+			Error:         isErrorReply(d.Headers),
 			Headers:       d.Headers,
 		}
 		responseChannel <- response
 		return rabbitmq.Ack
 	}, false, // Reply queues are not durable as they are made per-instance
+		WithExclusiveQueue(true),
+		WithAutoDeleteQueue(true),
+		WithRoutines(routines),
+		withInternal(),
 	)
 }
+
+// isErrorReply checks if the reply was sent with the error header
+func isErrorReply(headers map[string]any) bool {
+	isError, ok := headers[string(HeaderKeyError)].(bool)
+	return ok && isError
+}
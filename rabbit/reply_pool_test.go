@@ -1 +1,15 @@
 package rabbit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsErrorReply(t *testing.T) {
+	assert.False(t, isErrorReply(nil))
+	assert.False(t, isErrorReply(map[string]any{}))
+	assert.False(t, isErrorReply(map[string]any{"error": false}))
+	assert.False(t, isErrorReply(map[string]any{"error": "true"}))
+	assert.True(t, isErrorReply(map[string]any{"error": true}))
+}
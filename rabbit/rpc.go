@@ -0,0 +1,148 @@
+package rabbit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/wagslane/go-rabbitmq"
+	apiErrors "github.com/xBlaz3kx/DevX/errors"
+	grpc "github.com/xBlaz3kx/DevX/proto"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// RPCError is an error replied by the responder of a RPC request.
+// It matches ErrResponse and can be converted to an errors.ApiError with errors.As.
+type RPCError struct {
+	Code    grpc.ErrorCode
+	Message string
+}
+
+func NewRPCError(code grpc.ErrorCode, message string) *RPCError {
+	return &RPCError{Code: code, Message: message}
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Is makes the RPCError match ErrResponse
+func (e *RPCError) Is(target error) bool {
+	return target == ErrResponse
+}
+
+// As converts the RPCError to an errors.ApiError, so the HTTP error handler responds with the remote error
+func (e *RPCError) As(target any) bool {
+	apiErr, ok := target.(*apiErrors.ApiError)
+	if !ok {
+		return false
+	}
+
+	*apiErr = e.ApiError()
+	return true
+}
+
+// ApiError returns the API error with the error code as the internal code and the HTTP status mapped from the error code
+func (e *RPCError) ApiError() apiErrors.ApiError {
+	return apiErrors.New(int(e.Code), e.HTTPStatus(), e.Message)
+}
+
+// HTTPStatus returns the HTTP status code of the error code
+func (e *RPCError) HTTPStatus() int {
+	return HTTPStatusFromErrorCode(e.Code)
+}
+
+// HTTPStatusFromErrorCode maps the RPC error codes to HTTP status codes
+func HTTPStatusFromErrorCode(code grpc.ErrorCode) int {
+	switch code {
+	case grpc.ErrorCode_CannotSend:
+		return http.StatusBadGateway
+	case grpc.ErrorCode_PayloadError:
+		return http.StatusBadRequest
+	case grpc.ErrorCode_InterceptorError:
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Proto returns the error reply payload
+func (e *RPCError) Proto() *grpc.Error {
+	return NewError(e.Message, e.Code)
+}
+
+// ErrorToProto converts an error returned by a RPC handler to the error reply payload.
+// Errors other than RPCError are replied with the ApplicationError code.
+func ErrorToProto(err error) *grpc.Error {
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Proto()
+	}
+
+	return NewError(err.Error(), grpc.ErrorCode_ApplicationError)
+}
+
+// ErrorFromReply parses the body of an error reply to a RPCError.
+// If the body cannot be parsed, the returned error still matches ErrResponse.
+func ErrorFromReply(body []byte) error {
+	errPayload := &grpc.Error{}
+	if err := proto.Unmarshal(body, errPayload); err != nil {
+		return errors.Wrapf(ErrResponse, "failed to unmarshal error reply: %v", err)
+	}
+
+	return NewRPCError(errPayload.Code, errPayload.Message)
+}
+
+// InvokeRPC publishes the RPC request and unmarshals the reply into the response.
+// If the responder replied with an error, a RPCError is returned.
+func (pp *PublisherPool) InvokeRPC(ctx context.Context, topic Topic, request proto.Message, response proto.Message, options ...PublishOpt) error {
+	reply, err := pp.PublishRPC(ctx, topic, request, options...)
+	if err != nil {
+		return err
+	}
+
+	return proto.Unmarshal(reply, response)
+}
+
+// NewRPCHandler creates a consumer handler, which unmarshals the request, calls the handle function and responds to the
+// reply topic of the request with the response or the error. Requests that cannot be unmarshalled are replied with the
+// PayloadError code.
+func NewRPCHandler[Req proto.Message, Res proto.Message](publisher *PublisherPool, newRequest func() Req, handle func(ctx context.Context, request Req) (Res, error)) HandlerFunc {
+	return func(ctx context.Context, d rabbitmq.Delivery) rabbitmq.Action {
+		logger := publisher.obs.Log().Ctx(ctx).With(
+			zap.String("topic", d.RoutingKey),
+			zap.String("correlationId", d.CorrelationId),
+		)
+
+		var (
+			response proto.Message
+			err      error
+		)
+
+		request := newRequest()
+		if unmarshalErr := proto.Unmarshal(d.Body, request); unmarshalErr != nil {
+			err = NewRPCError(grpc.ErrorCode_PayloadError, unmarshalErr.Error())
+		} else {
+			response, err = handle(ctx, request)
+		}
+
+		// Nobody is waiting for the reply
+		if d.ReplyTo == "" {
+			return rabbitmq.Ack
+		}
+
+		if err != nil {
+			err = publisher.RespondWithError(ctx, d.CorrelationId, Topic(d.ReplyTo), ErrorToProto(err))
+		} else {
+			err = publisher.Respond(ctx, d.CorrelationId, Topic(d.ReplyTo), response)
+		}
+
+		if err != nil {
+			logger.Error("Unable to respond to rabbit request", zap.Error(err))
+		}
+
+		return rabbitmq.Ack
+	}
+}
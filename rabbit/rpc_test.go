@@ -0,0 +1,84 @@
+package rabbit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiErrors "github.com/xBlaz3kx/DevX/errors"
+	grpc "github.com/xBlaz3kx/DevX/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestRPCError(t *testing.T) {
+	err := NewRPCError(grpc.ErrorCode_PayloadError, "invalid request")
+	assert.ErrorIs(t, err, ErrResponse)
+	assert.Equal(t, grpc.ErrorCode_PayloadError, err.Proto().Code)
+	assert.Equal(t, "invalid request", err.Proto().Message)
+}
+
+func TestErrorToProto(t *testing.T) {
+	rpcErr := NewRPCError(grpc.ErrorCode_PayloadError, "invalid request")
+	assert.Equal(t, grpc.ErrorCode_PayloadError, ErrorToProto(errors.Wrap(rpcErr, "handler")).Code)
+
+	payload := ErrorToProto(errors.New("boom"))
+	assert.Equal(t, grpc.ErrorCode_ApplicationError, payload.Code)
+	assert.Equal(t, "boom", payload.Message)
+}
+
+func TestErrorFromReply(t *testing.T) {
+	body, err := proto.Marshal(NewError("not found", grpc.ErrorCode_ApplicationError))
+	require.NoError(t, err)
+
+	replyErr := ErrorFromReply(body)
+	assert.ErrorIs(t, replyErr, ErrResponse)
+
+	var rpcErr *RPCError
+	require.ErrorAs(t, replyErr, &rpcErr)
+	assert.Equal(t, grpc.ErrorCode_ApplicationError, rpcErr.Code)
+	assert.Equal(t, "not found", rpcErr.Message)
+
+	assert.ErrorIs(t, ErrorFromReply([]byte{0xff}), ErrResponse)
+}
+
+func TestRPCErrorAsApiError(t *testing.T) {
+	err := errors.Wrap(NewRPCError(grpc.ErrorCode_PayloadError, "invalid request"), "failed to get status")
+
+	var apiErr apiErrors.ApiError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode())
+	assert.Equal(t, int(grpc.ErrorCode_PayloadError), apiErr.InternalCode())
+	assert.Equal(t, "invalid request", apiErr.Message())
+}
+
+func TestHTTPStatusFromErrorCode(t *testing.T) {
+	assert.Equal(t, http.StatusBadGateway, HTTPStatusFromErrorCode(grpc.ErrorCode_CannotSend))
+	assert.Equal(t, http.StatusBadRequest, HTTPStatusFromErrorCode(grpc.ErrorCode_PayloadError))
+	assert.Equal(t, http.StatusForbidden, HTTPStatusFromErrorCode(grpc.ErrorCode_InterceptorError))
+	assert.Equal(t, http.StatusInternalServerError, HTTPStatusFromErrorCode(grpc.ErrorCode_ApplicationError))
+	assert.Equal(t, http.StatusInternalServerError, HTTPStatusFromErrorCode(grpc.ErrorCode(42)))
+}
+
+func TestWaitReply(t *testing.T) {
+	body, err := proto.Marshal(NewError("not found", grpc.ErrorCode_ApplicationError))
+	require.NoError(t, err)
+
+	replies := make(chan ReplyResponse, 1)
+	replies <- ReplyResponse{Body: body, Error: true}
+
+	reply, err := waitReply(context.Background(), replies)
+	assert.Equal(t, body, reply)
+	assert.ErrorIs(t, err, ErrResponse)
+
+	var rpcErr *RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, "not found", rpcErr.Message)
+
+	replies <- ReplyResponse{Body: []byte("ok")}
+	reply, err = waitReply(context.Background(), replies)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("ok"), reply)
+}
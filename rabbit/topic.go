@@ -15,7 +15,8 @@ func (t Topic) String() string {
 }
 
 const (
-	replyBase TopicWord = "REPLY"
+	replyBase     TopicWord = "REPLY"
+	broadcastBase TopicWord = "BROADCAST"
 )
 
 type TopicBuilder struct {
@@ -1,6 +1,7 @@
 package rabbit
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -26,3 +27,31 @@ func TestTopicWord_String(t *testing.T) {
 
 	assert.Equal(t, "word2", word.String())
 }
+
+func TestNewReplyTopic(t *testing.T) {
+	topic1, err := newReplyTopic("service")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(topic1.String(), "service.REPLY."))
+
+	// Every instance must get its own reply topic
+	topic2, err := newReplyTopic("service")
+	assert.NoError(t, err)
+	assert.NotEqual(t, topic1, topic2)
+}
+
+func TestIsDirectReplyTopic(t *testing.T) {
+	assert.True(t, isDirectReplyTopic("amq.rabbitmq.reply-to"))
+	assert.True(t, isDirectReplyTopic("amq.rabbitmq.reply-to.g1h2AA5yZXBseUAxNjY5NzQ5OQAAB0wAAAAAYXJrOAAAAA=="))
+	assert.False(t, isDirectReplyTopic("service.REPLY.host"))
+}
+
+func TestNewBroadcastQueueName(t *testing.T) {
+	queue1, err := newBroadcastQueueName("service")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(queue1, "service.BROADCAST."))
+
+	// Every subscription must get its own queue
+	queue2, err := newBroadcastQueueName("service")
+	assert.NoError(t, err)
+	assert.NotEqual(t, queue1, queue2)
+}
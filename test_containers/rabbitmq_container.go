@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"context"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	rabbitT "github.com/testcontainers/testcontainers-go/modules/rabbitmq"
+)
+
+type RabbitMQContainer struct {
+	*rabbitT.RabbitMQContainer
+}
+
+func NewRabbitMQContainer(ctx context.Context) (*RabbitMQContainer, error) {
+	// Spin up a test RabbitMQ container with the management plugin
+	rabbitContainer, err := rabbitT.Run(ctx, "rabbitmq:3.13-management-alpine")
+	if err != nil {
+		return nil, err
+	}
+
+	return &RabbitMQContainer{RabbitMQContainer: rabbitContainer}, nil
+}
+
+// DeclareExchanges declares durable topic exchanges, as the rabbit client expects the exchanges to exist
+func (r *RabbitMQContainer) DeclareExchanges(ctx context.Context, exchanges ...string) error {
+	url, err := r.AmqpURL(ctx)
+	if err != nil {
+		return err
+	}
+
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	channel, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer channel.Close()
+
+	for _, exchange := range exchanges {
+		err = channel.ExchangeDeclare(exchange, "topic", true, false, false, false, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -39,18 +39,25 @@ func (t *TLS) ToTlsConfig() (*tls.Config, error) {
 		if err != nil {
 			return nil, err
 		} else if !certPool.AppendCertsFromPEM(caCert) {
-			return nil, err
+			return nil, errors.New("failed to parse the root certificate")
 		}
 	}
 
+	tlsConfig := &tls.Config{
+		RootCAs: certPool,
+	}
+
+	// The client certificate is optional, e.g. when the server only authenticates with a username and password
+	if t.CertificatePath == "" && t.PrivateKeyPath == "" {
+		return tlsConfig, nil
+	}
+
 	// Load client certificate & private key
 	certificate, err := tls.LoadX509KeyPair(t.CertificatePath, t.PrivateKeyPath)
 	if err != nil {
 		return nil, err
 	}
 
-	return &tls.Config{
-		RootCAs:      certPool,
-		Certificates: []tls.Certificate{certificate},
-	}, nil
+	tlsConfig.Certificates = []tls.Certificate{certificate}
+	return tlsConfig, nil
 }